@@ -4,81 +4,103 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 	"wowstatistician/auth"
 	"wowstatistician/characters"
 	"wowstatistician/guilds"
 	"wowstatistician/helpers"
 	"wowstatistician/helpers/databases"
 	"wowstatistician/leatherboards"
+	"wowstatistician/models"
 	"wowstatistician/realms"
 )
 
-// SaveRaidProfiles save player profiles from raid leatherboard to a db
-func SaveRaidProfiles(region string, raid string) error {
+// SaveRaidProfiles save player profiles from raid leatherboard to a store
+func SaveRaidProfiles(store databases.Store, region string, raid string) error {
 	token, err := auth.CreateToken()
 	if err != nil {
 		return errors.New("cmd: could not save raid profiles - " + err.Error())
 	}
-	db, err := databases.OpenDB("databases/raid")
-	if err != nil {
-		return errors.New("cmd: could not save raid profiles - " + err.Error())
-	}
-	defer db.Close()
+	crawl := databases.NewSnapshot()
 	fmt.Printf("--- Getting leatherboard for region: %v and raid: %v ---\n", region, raid)
 	raidLeatherboard, err := leatherboards.GetRaidLeatherboard(token, region, raid)
 	if err != nil {
 		return errors.New("cmd: could not save raid profiles - " + err.Error())
 	}
-	entriesNumber := 0
-	// Loop:
+	regionEntries := []leatherboards.Entry{}
 	for _, entry := range raidLeatherboard.Entries {
 		if entry.Region == region {
-			entry.Guild.Slug = guilds.MakeGuildSlug(entry.Guild.Name)
-			fmt.Printf("--- Getting roster for guild: %v from: %v ---\n", entry.Guild.Slug, entry.Guild.Realm.Slug)
-			guildRoster, err := guilds.GetGuildRoster(token, region, entry.Guild.Realm.Slug, entry.Guild.Slug)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			for _, member := range guildRoster.Members {
-				if member.Character.Level == 120 {
-					characterProfile, err := characters.GetCharacterProfile(token, region, entry.Guild.Realm.Slug, strings.ToLower(member.Character.Name))
+			regionEntries = append(regionEntries, entry)
+		}
+	}
+	sort.SliceStable(regionEntries, func(i, j int) bool {
+		return regionEntries[i].Timestamp < regionEntries[j].Timestamp
+	})
+	entriesNumber := 0
+	// Loop:
+	for i, entry := range regionEntries {
+		entry.Guild.Slug = guilds.MakeGuildSlug(entry.Guild.Name)
+		guild := makeGuild(raid, i+1, entry)
+		err := store.WriteGuild(guild)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		fmt.Printf("--- Getting roster for guild: %v from: %v ---\n", entry.Guild.Slug, entry.Guild.Realm.Slug)
+		guildRoster, err := guilds.GetGuildRoster(token, region, entry.Guild.Realm.Slug, entry.Guild.Slug)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for _, member := range guildRoster.Members {
+			if member.Character.Level == 120 {
+				characterProfile, err := characters.GetCharacterProfile(token, region, entry.Guild.Realm.Slug, strings.ToLower(member.Character.Name))
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				if helpers.CheckValidProfile(*characterProfile) {
+					fmt.Printf("Saving %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
+					err := store.WriteProfile("", *characterProfile)
 					if err != nil {
 						log.Println(err)
 						continue
 					}
-					if helpers.CheckValidProfile(*characterProfile) {
-						fmt.Printf("Saving %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
-						err := databases.WriteProfileToDb(db, *characterProfile)
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-						entriesNumber++
+					err = store.WriteMember(models.Member{ID: characterProfile.ID, GuildID: guild.ID, Raid: raid, RosterRank: member.Rank})
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					err = store.WriteProvenance(makeProvenance("", raid+" hall of fame", crawl, characterProfile.ID))
+					if err != nil {
+						log.Println(err)
+						continue
 					}
-					// if entriesNumber >= 10 {
-					// 	break Loop
-					// }
+					entriesNumber++
 				}
+				// if entriesNumber >= 10 {
+				// 	break Loop
+				// }
 			}
 		}
 	}
+	err = store.WriteCrawl(raid, crawl)
+	if err != nil {
+		return errors.New("cmd: could not save raid profiles - " + err.Error())
+	}
 	fmt.Printf("--- Added %v entries ---\n", entriesNumber)
 	return nil
 }
 
-// SaveMythicProfiles save player profiles from mythic leatherboard to a db
-func SaveMythicProfiles(region string) error {
+// SaveMythicProfiles save player profiles and runs from mythic leatherboard to a store
+func SaveMythicProfiles(store databases.Store, region string) error {
 	token, err := auth.CreateToken()
 	if err != nil {
 		return errors.New("cmd: could not save mythic profiles - " + err.Error())
 	}
-	db, err := databases.OpenDB("databases/mythic")
-	if err != nil {
-		return errors.New("cmd: could not save mythic profiles - " + err.Error())
-	}
-	defer db.Close()
+	crawl := databases.NewSnapshot()
 	fmt.Printf("--- Getting connected realms index for region: %v ---\n", region)
 	connectedRealmsIndex, err := realms.GetConnectedRealmsIndex(token, region)
 	if err != nil {
@@ -108,6 +130,7 @@ func SaveMythicProfiles(region string) error {
 				if leatherboard.Name != "" {
 					fmt.Printf("--- Getting details for leatherboard: %v ---\n", leatherboard.Name)
 					for _, group := range leatherboard.LeadingGroups {
+						run := makeRun(*leatherboard, group)
 						for _, member := range group.Members {
 							var characterProfile characters.CharacterProfile
 							characterProfile.Name = member.Profile.Name
@@ -123,9 +146,21 @@ func SaveMythicProfiles(region string) error {
 							}
 							characterProfile.ActiveSpec = *activeSpec
 							characterProfile.CharacterClass = characterProfile.ActiveSpec.PlayableClass
+							run.Members = append(run.Members, &models.RunMember{
+								ID:    characterProfile.ID,
+								Name:  characterProfile.Name,
+								Realm: characterProfile.Realm.Slug,
+								Class: characterProfile.CharacterClass.Name,
+								Spec:  characterProfile.ActiveSpec.Name,
+							})
 							if helpers.CheckValidProfile(characterProfile) {
 								fmt.Printf("Saving %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
-								err := databases.WriteProfileToDb(db, characterProfile)
+								err := store.WriteProfile("", characterProfile)
+								if err != nil {
+									log.Println(err)
+									continue
+								}
+								err = store.WriteProvenance(makeProvenance("", leatherboard.Name, crawl, characterProfile.ID))
 								if err != nil {
 									log.Println(err)
 									continue
@@ -136,161 +171,206 @@ func SaveMythicProfiles(region string) error {
 							// 	break Loop
 							// }
 						}
+						err := store.WriteRun(run)
+						if err != nil {
+							log.Println(err)
+						}
 					}
 				}
 			}
 		}
 	}
+	err = store.WriteCrawl("", crawl)
+	if err != nil {
+		return errors.New("cmd: could not save mythic profiles - " + err.Error())
+	}
 	fmt.Printf("--- Added %v entries ---\n", entriesNumber)
 	return nil
 }
 
-// SaveArenaProfiles save player profiles from arena leatherboard to a db
-func SaveArenaProfiles(region string) error {
+// SaveArenaProfiles save player profiles from every arena leatherboard to a store, optionally restricted to provided brackets
+func SaveArenaProfiles(store databases.Store, region string, brackets []string) error {
 	token, err := auth.CreateToken()
 	if err != nil {
 		return errors.New("cmd: could not save arena profiles - " + err.Error())
 	}
-	db, err := databases.OpenDB("databases/arena")
+	entriesNumber, err := savePvpProfiles(token, store, region, func(bracket string) bool {
+		return bracket != "rbg" && helpers.MatchBracket(bracket, brackets)
+	})
 	if err != nil {
 		return errors.New("cmd: could not save arena profiles - " + err.Error())
 	}
-	defer db.Close()
+	fmt.Printf("--- Added %v entries ---\n", entriesNumber)
+	return nil
+}
+
+// SaveRbgProfiles save player profiles from rbg leatherboard to a store
+func SaveRbgProfiles(store databases.Store, region string) error {
+	token, err := auth.CreateToken()
+	if err != nil {
+		return errors.New("cmd: could not save rbg profiles - " + err.Error())
+	}
+	entriesNumber, err := savePvpProfiles(token, store, region, func(bracket string) bool {
+		return bracket == "rbg"
+	})
+	if err != nil {
+		return errors.New("cmd: could not save rbg profiles - " + err.Error())
+	}
+	fmt.Printf("--- Added %v entries ---\n", entriesNumber)
+	return nil
+}
+
+// savePvpProfiles save player profiles from every current season pvp leatherboard accepted by keep, tagged by bracket
+func savePvpProfiles(token string, store databases.Store, region string, keep func(bracket string) bool) (int, error) {
 	fmt.Printf("--- Getting pvp season index for region: %v ---\n", region)
 	pvpSeasonsIndex, err := leatherboards.GetPvpSeasonsIndex(token, region)
 	if err != nil {
-		return errors.New("cmd: could not save arena profiles - " + err.Error())
+		return 0, err
 	}
 	fmt.Printf("--- Getting current pvp season---\n")
 	pvpSeason, err := leatherboards.GetPvpSeason(token, pvpSeasonsIndex.CurrentSeason.Key)
 	if err != nil {
-		return errors.New("cmd: could not save arena profiles - " + err.Error())
+		return 0, err
 	}
 	fmt.Printf("--- Getting pvp leatherboards---\n")
 	pvpLeatherboards, err := leatherboards.GetPvpLeatherboards(token, pvpSeason.Leaderboards)
 	if err != nil {
-		return errors.New("cmd: could not save arena profiles - " + err.Error())
+		return 0, err
 	}
+	fmt.Printf("--- Getting pvp rewards---\n")
+	pvpRewards, err := leatherboards.GetPvpRewards(token, pvpSeason.Rewards)
+	if err != nil {
+		log.Println(err)
+		pvpRewards = &leatherboards.PvpRewards{}
+	}
+	crawl := databases.NewSnapshot()
 	entriesNumber := 0
 	for _, leatherboard := range pvpLeatherboards.Leaderboards {
-		if leatherboard.Name == "2v2" {
-			fmt.Printf("--- Getting 2v2 data---\n")
-			vTwoLeatherboard, err := leatherboards.GetPvpLeatherboard(token, leatherboard.Key)
+		if leatherboard.Name == "" || !keep(leatherboard.Name) {
+			continue
+		}
+		fmt.Printf("--- Getting %v data---\n", leatherboard.Name)
+		pvpLeatherboard, err := leatherboards.GetPvpLeatherboard(token, leatherboard.Key)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		cutoff := rankOneCutoff(*pvpRewards, pvpLeatherboard.Bracket.Type)
+		if cutoff > 0 {
+			err = store.WriteCutoff(leatherboard.Name, cutoff)
 			if err != nil {
 				log.Println(err)
-			} else {
-				// Loop:
-				for _, entry := range vTwoLeatherboard.Entries {
-					characterProfile, err := characters.GetCharacterProfile(token, region, entry.Character.Realm.Slug, strings.ToLower(entry.Character.Name))
-					if err != nil {
-						log.Println(err)
-						continue
-					}
-					if helpers.CheckValidProfile(*characterProfile) {
-						fmt.Printf("Saving %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
-						err := databases.WriteProfileToDb(db, *characterProfile)
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-						entriesNumber++
-					}
-					// if entriesNumber >= 10 {
-					// 	break Loop
-					// }
-				}
 			}
 		}
-		if leatherboard.Name == "3v3" {
-			fmt.Printf("--- Getting 3v3 data---\n")
-			vThreeLeatherboard, err := leatherboards.GetPvpLeatherboard(token, leatherboard.Key)
+		// Loop:
+		for _, entry := range pvpLeatherboard.Entries {
+			characterProfile, err := characters.GetCharacterProfile(token, region, entry.Character.Realm.Slug, strings.ToLower(entry.Character.Name))
 			if err != nil {
 				log.Println(err)
-			} else {
-				// Loop:
-				for _, entry := range vThreeLeatherboard.Entries {
-					characterProfile, err := characters.GetCharacterProfile(token, region, entry.Character.Realm.Slug, strings.ToLower(entry.Character.Name))
-					if err != nil {
-						log.Println(err)
-						continue
-					}
-					if helpers.CheckValidProfile(*characterProfile) {
-						fmt.Printf("Saving %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
-						err := databases.WriteProfileToDb(db, *characterProfile)
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-						entriesNumber++
-					}
-					// if entriesNumber >= 10 {
-					// 	break Loop
-					// }
+				continue
+			}
+			if helpers.CheckValidProfile(*characterProfile) {
+				fmt.Printf("Saving %v as a %v %v with id: %v in bracket: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID, leatherboard.Name)
+				err := store.WriteProfile(leatherboard.Name, *characterProfile)
+				if err != nil {
+					log.Println(err)
+					continue
 				}
+				err = store.WriteLadder(makeLadder(leatherboard.Name, pvpLeatherboard.Season.ID, characterProfile.ID, entry))
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				err = store.WriteProvenance(makeProvenance(leatherboard.Name, fmt.Sprintf("%v season %v", leatherboard.Name, pvpLeatherboard.Season.ID), crawl, characterProfile.ID))
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				entriesNumber++
 			}
+			// if entriesNumber >= 10 {
+			// 	break Loop
+			// }
+		}
+		err = store.WriteCrawl(leatherboard.Name, crawl)
+		if err != nil {
+			log.Println(err)
 		}
 	}
-	fmt.Printf("--- Added %v entries ---\n", entriesNumber)
-	return nil
+	return entriesNumber, nil
 }
 
-// SaveRbgProfiles save player profiles from rbg leatherboard to a db
-func SaveRbgProfiles(region string) error {
-	token, err := auth.CreateToken()
-	if err != nil {
-		return errors.New("cmd: could not save rbg profiles - " + err.Error())
-	}
-	db, err := databases.OpenDB("databases/rbg")
-	if err != nil {
-		return errors.New("cmd: could not save rbg profiles - " + err.Error())
+// rankOneCutoff return the lowest rank 1 rating cutoff of a bracket type across its faction and spec rewards, 0 if none is published
+func rankOneCutoff(pvpRewards leatherboards.PvpRewards, bracketType string) int {
+	cutoff := 0
+	for _, reward := range pvpRewards.Rewards {
+		if reward.Bracket.Type != bracketType || reward.RatingCutoff <= 0 {
+			continue
+		}
+		if cutoff == 0 || reward.RatingCutoff < cutoff {
+			cutoff = reward.RatingCutoff
+		}
 	}
-	defer db.Close()
-	fmt.Printf("--- Getting pvp season index for region: %v ---\n", region)
-	pvpSeasonsIndex, err := leatherboards.GetPvpSeasonsIndex(token, region)
-	if err != nil {
-		return errors.New("cmd: could not save rbg profiles - " + err.Error())
+	return cutoff
+}
+
+// makeProvenance return the provenance of a profile listed by provided leatherboard during provided crawl, fetched now
+func makeProvenance(bracket string, leatherboard string, crawl int64, ID int) models.Provenance {
+	return models.Provenance{
+		ID:           ID,
+		Bracket:      bracket,
+		Leatherboard: leatherboard,
+		Crawl:        crawl,
+		FetchedAt:    time.Now().Unix(),
 	}
-	fmt.Printf("--- Getting current pvp season---\n")
-	pvpSeason, err := leatherboards.GetPvpSeason(token, pvpSeasonsIndex.CurrentSeason.Key)
-	if err != nil {
-		return errors.New("cmd: could not save rbg profiles - " + err.Error())
+}
+
+// makeLadder return the ladder context of a pvp leatherboard entry for provided bracket, season and character ID
+func makeLadder(bracket string, season int, ID int, entry leatherboards.Entry) models.Ladder {
+	return models.Ladder{
+		ID:      ID,
+		Bracket: bracket,
+		Season:  season,
+		Rating:  entry.Rating,
+		Rank:    entry.Rank,
+		Tier:    entry.Tier.ID,
+		Played:  entry.SeasonMatchStatistics.Played,
+		Won:     entry.SeasonMatchStatistics.Won,
+		Lost:    entry.SeasonMatchStatistics.Lost,
 	}
-	fmt.Printf("--- Getting pvp leatherboards---\n")
-	pvpLeatherboards, err := leatherboards.GetPvpLeatherboards(token, pvpSeason.Leaderboards)
-	if err != nil {
-		return errors.New("cmd: could not save rbg profiles - " + err.Error())
+}
+
+// makeGuild return the guild of a raid hall of fame entry for provided raid and rank of kill within the region
+func makeGuild(raid string, regionRank int, entry leatherboards.Entry) models.Guild {
+	return models.Guild{
+		ID:         entry.Guild.ID,
+		Name:       entry.Guild.Name,
+		Realm:      entry.Guild.Realm.Slug,
+		Faction:    entry.Faction.Type,
+		Raid:       raid,
+		Rank:       entry.Rank,
+		RegionRank: regionRank,
+		Timestamp:  entry.Timestamp,
 	}
-	entriesNumber := 0
-	for _, leatherboard := range pvpLeatherboards.Leaderboards {
-		if leatherboard.Name == "rbg" {
-			fmt.Printf("--- Getting rbg data---\n")
-			vTwoLeatherboard, err := leatherboards.GetPvpLeatherboard(token, leatherboard.Key)
-			if err != nil {
-				log.Println(err)
-			} else {
-				// Loop:
-				for _, entry := range vTwoLeatherboard.Entries {
-					characterProfile, err := characters.GetCharacterProfile(token, region, entry.Character.Realm.Slug, strings.ToLower(entry.Character.Name))
-					if err != nil {
-						log.Println(err)
-						continue
-					}
-					if helpers.CheckValidProfile(*characterProfile) {
-						fmt.Printf("Saving %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
-						err := databases.WriteProfileToDb(db, *characterProfile)
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-						entriesNumber++
-					}
-					// if entriesNumber >= 10 {
-					// 	break Loop
-					// }
-				}
-			}
+}
+
+// makeRun return the mythic+ run of a leading group of provided leatherboard, without its members
+// A run is identified by its dungeon, completion time and lowest member ID as it is listed by the leatherboard of every member realm
+func makeRun(leatherboard leatherboards.MythicLeatherboard, group leatherboards.LeadingGroup) models.Run {
+	leaderID := 0
+	for _, member := range group.Members {
+		if leaderID == 0 || member.Profile.ID < leaderID {
+			leaderID = member.Profile.ID
 		}
 	}
-	fmt.Printf("--- Added %v entries ---\n", entriesNumber)
-	return nil
+	return models.Run{
+		ID:                 fmt.Sprintf("%d-%d-%d", leatherboard.MapChallengeModeID, group.CompletedTimestamp, leaderID),
+		Dungeon:            leatherboard.Map.Name,
+		DungeonID:          leatherboard.MapChallengeModeID,
+		Period:             leatherboard.Period,
+		Ranking:            group.Ranking,
+		KeystoneLevel:      group.KeystoneLevel,
+		Duration:           group.Duration,
+		CompletedTimestamp: group.CompletedTimestamp,
+	}
 }
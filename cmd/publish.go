@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"wowstatistician/models"
+
+	"github.com/imroc/req"
+)
+
+// PublishStats push freshly computed stats to a running serve command at provided url, authenticated with its publish token
+func PublishStats(url string, token string, name string, stats models.Stats) error {
+	authstr := fmt.Sprintf("Bearer %s", token)
+	header := req.Header{
+		"Authorization": authstr,
+	}
+	urlstr := fmt.Sprintf("%s/stats/%s", strings.TrimSuffix(url, "/"), name)
+	request, err := req.Post(urlstr, header, req.BodyJSON(stats))
+	if err != nil {
+		return errors.New("cmd: could not publish stats - " + err.Error())
+	}
+	if request.Response().StatusCode != http.StatusNoContent {
+		return errors.New("cmd: could not publish stats - server answered " + request.Response().Status + ": " + request.String())
+	}
+	return nil
+}
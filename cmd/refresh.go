@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wowstatistician/auth"
+	"wowstatistician/characters"
+	"wowstatistician/helpers"
+	"wowstatistician/helpers/databases"
+	"wowstatistician/models"
+)
+
+// RefreshProfiles fetch again the profiles of a store last fetched longer than olderThan ago, or never recorded as fetched
+// Refreshed profiles keep the leatherboard and crawl they were listed by, only their fetch time change
+func RefreshProfiles(store databases.Store, region string, olderThan time.Duration) error {
+	token, err := auth.CreateToken()
+	if err != nil {
+		return errors.New("cmd: could not refresh profiles - " + err.Error())
+	}
+	limit := time.Now().Add(-olderThan).Unix()
+	stale := []databases.Entry{}
+	err = store.Entries("", func(entry databases.Entry) error {
+		if entry.Provenance == nil || entry.Provenance.FetchedAt < limit {
+			stale = append(stale, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("cmd: could not refresh profiles - " + err.Error())
+	}
+	fmt.Printf("--- Refreshing %v stale profiles ---\n", len(stale))
+	entriesNumber := 0
+	for _, entry := range stale {
+		provenance := refreshedProvenance(entry)
+		characterProfile, err := characters.GetCharacterProfile(token, region, entry.Profile.Realm.Slug, strings.ToLower(entry.Profile.Name))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if !helpers.CheckValidProfile(*characterProfile) {
+			continue
+		}
+		fmt.Printf("Refreshing %v as a %v %v with id: %v\n", characterProfile.Name, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.Name, characterProfile.ID)
+		err = store.WriteProfile(provenance.Bracket, *characterProfile)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		err = store.WriteProvenance(provenance)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		entriesNumber++
+	}
+	fmt.Printf("--- Refreshed %v entries ---\n", entriesNumber)
+	return nil
+}
+
+// refreshedProvenance return the provenance of an entry fetched now, guessing its bracket from its ladder when none was recorded
+func refreshedProvenance(entry databases.Entry) models.Provenance {
+	var provenance models.Provenance
+	if entry.Provenance != nil {
+		provenance = *entry.Provenance
+	} else {
+		provenance.ID = entry.Profile.ID
+		if entry.Ladder != nil {
+			provenance.Bracket = entry.Ladder.Bracket
+		}
+	}
+	provenance.FetchedAt = time.Now().Unix()
+	return provenance
+}
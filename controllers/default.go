@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"wowstatistician/helpers/databases"
+
 	"github.com/astaxie/beego"
-	"github.com/dgraph-io/badger/v2"
 )
 
 var (
-	Db *badger.DB
+	// Cache hold the served stats, reloaded from the store while serving
+	Cache *databases.StatsCache
+	// PublishToken is the bearer token stats must be published with, publishing being disabled when empty
+	PublishToken string
 )
 
 type DefaultController struct {
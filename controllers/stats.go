@@ -1,7 +1,12 @@
 package controllers
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"wowstatistician/helpers"
 	"wowstatistician/helpers/databases"
+	"wowstatistician/models"
 
 	"github.com/astaxie/beego"
 )
@@ -10,9 +15,25 @@ type StatsController struct {
 	beego.Controller
 }
 
+// statsName return the stats name of the request, restricted to the region query param when provided
+func (this *StatsController) statsName() string {
+	return databases.RegionName(this.Ctx.Input.Param(":dbname"), this.GetString("region"))
+}
+
 func (this *StatsController) GetStats() {
-	dbname := this.Ctx.Input.Param(":dbname")
-	stats, err := databases.GetStatsFromDb(Db, dbname)
+	dbname := this.statsName()
+	snapshot, err := this.GetInt64("snapshot", 0)
+	if err != nil {
+		this.Ctx.Output.SetStatus(400)
+		this.Ctx.Output.Body([]byte("invalid snapshot: " + this.GetString("snapshot")))
+		return
+	}
+	var stats *models.Stats
+	if snapshot == 0 {
+		stats, err = Cache.Store().ReadStats(dbname)
+	} else {
+		stats, err = Cache.Store().ReadSnapshot(dbname, snapshot)
+	}
 	if err != nil {
 		this.Ctx.Output.SetStatus(404)
 		this.Ctx.Output.Body([]byte(err.Error()))
@@ -21,3 +42,125 @@ func (this *StatsController) GetStats() {
 	this.Data["json"] = stats
 	this.ServeJSON()
 }
+
+// GetSnapshots serve the snapshots of a stats db, oldest first
+func (this *StatsController) GetSnapshots() {
+	dbname := this.statsName()
+	snapshots, err := Cache.Store().Snapshots(dbname)
+	if err != nil {
+		this.Ctx.Output.SetStatus(500)
+		this.Ctx.Output.Body([]byte(err.Error()))
+		return
+	}
+	this.Data["json"] = snapshots
+	this.ServeJSON()
+}
+
+// GetHistory serve the class and spec shares of a stats db over its snapshots
+// Shares can be restricted with the class and spec query params and snapshots with from and to, as unix timestamps or dates
+func (this *StatsController) GetHistory() {
+	dbname := this.statsName()
+	from, to := int64(0), int64(0)
+	var err error
+	if value := this.GetString("from"); value != "" {
+		from, err = databases.ParseSnapshot(value)
+		if err != nil {
+			this.Ctx.Output.SetStatus(400)
+			this.Ctx.Output.Body([]byte(err.Error()))
+			return
+		}
+	}
+	if value := this.GetString("to"); value != "" {
+		to, err = databases.ParseSnapshotUntil(value)
+		if err != nil {
+			this.Ctx.Output.SetStatus(400)
+			this.Ctx.Output.Body([]byte(err.Error()))
+			return
+		}
+	}
+	history, err := databases.History(Cache.Store(), dbname, this.GetString("class"), this.GetString("spec"), from, to)
+	if err != nil {
+		this.Ctx.Output.SetStatus(500)
+		this.Ctx.Output.Body([]byte(err.Error()))
+		return
+	}
+	this.Data["json"] = history
+	this.ServeJSON()
+}
+
+// GetDiff serve the changes of class and spec shares between two snapshots of a stats db
+// Snapshots are picked with the from and to query params, resolved to the nearest snapshots taken within them, the number of highlighted specs with movers and the output with format, json by default
+func (this *StatsController) GetDiff() {
+	dbname := this.statsName()
+	from, to := int64(0), int64(0)
+	var err error
+	if value := this.GetString("from"); value != "" {
+		from, err = databases.ParseSnapshot(value)
+		if err != nil {
+			this.Ctx.Output.SetStatus(400)
+			this.Ctx.Output.Body([]byte(err.Error()))
+			return
+		}
+	}
+	if value := this.GetString("to"); value != "" {
+		to, err = databases.ParseSnapshotUntil(value)
+		if err != nil {
+			this.Ctx.Output.SetStatus(400)
+			this.Ctx.Output.Body([]byte(err.Error()))
+			return
+		}
+	}
+	movers, err := this.GetInt("movers", 5)
+	if err != nil {
+		this.Ctx.Output.SetStatus(400)
+		this.Ctx.Output.Body([]byte("invalid movers: " + this.GetString("movers")))
+		return
+	}
+	diff, err := databases.Diff(Cache.Store(), dbname, from, to, movers)
+	if err != nil {
+		this.Ctx.Output.SetStatus(404)
+		this.Ctx.Output.Body([]byte(err.Error()))
+		return
+	}
+	format := this.GetString("format", "json")
+	if format == "json" {
+		this.Data["json"] = diff
+		this.ServeJSON()
+		return
+	}
+	var buffer bytes.Buffer
+	err = helpers.WriteDiff(&buffer, diff, format)
+	if err != nil {
+		this.Ctx.Output.SetStatus(400)
+		this.Ctx.Output.Body([]byte(err.Error()))
+		return
+	}
+	this.Ctx.Output.Header("Content-Type", "text/plain; charset=utf-8")
+	this.Ctx.Output.Body(buffer.Bytes())
+}
+
+// PostStats publish freshly computed stats of a stats db, served right away without waiting for the next reload of the cache
+// The request must carry the publish token as a bearer token
+func (this *StatsController) PostStats() {
+	dbname := this.Ctx.Input.Param(":dbname")
+	authorization := this.Ctx.Input.Header("Authorization")
+	if PublishToken == "" || subtle.ConstantTimeCompare([]byte(authorization), []byte("Bearer "+PublishToken)) != 1 {
+		this.Ctx.Output.SetStatus(401)
+		this.Ctx.Output.Body([]byte("invalid or missing publish token"))
+		return
+	}
+	var stats models.Stats
+	err := json.Unmarshal(this.Ctx.Input.RequestBody, &stats)
+	if err != nil {
+		this.Ctx.Output.SetStatus(400)
+		this.Ctx.Output.Body([]byte("invalid stats: " + err.Error()))
+		return
+	}
+	err = Cache.Publish(dbname, stats)
+	if err != nil {
+		this.Ctx.Output.SetStatus(500)
+		this.Ctx.Output.Body([]byte(err.Error()))
+		return
+	}
+	this.Ctx.Output.SetStatus(204)
+}
@@ -8,6 +8,8 @@ require (
 	github.com/dgraph-io/badger/v2 v2.0.3
 	github.com/gopherjs/gopherjs v0.0.0-20200217142428-fce0ec30dd00
 	github.com/imroc/req v0.3.0
+	github.com/mattn/go-sqlite3 v1.14.0
 	github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
 	github.com/urfave/cli/v2 v2.2.0
+	github.com/xitongsys/parquet-go v1.6.2
 )
@@ -1,6 +1,11 @@
 package helpers
 
-import "wowstatistician/characters"
+import (
+	"errors"
+	"strings"
+	"wowstatistician/characters"
+	"wowstatistician/models"
+)
 
 // CheckValidProfile check a profile and return true if it contains all the variable requiered for stats
 func CheckValidProfile(characterProfile characters.CharacterProfile) bool {
@@ -9,3 +14,43 @@ func CheckValidProfile(characterProfile characters.CharacterProfile) bool {
 	}
 	return false
 }
+
+// CheckValidProfileRow check an imported profile row and return why it can not be imported, nil if it can
+// Besides the fields requiered for stats and the realm, class and spec ids profiles are stored against, counts must not be negative and a ladder entry can not have more wins and losses than games
+func CheckValidProfileRow(row models.ProfileRow) error {
+	switch {
+	case row.ID <= 0:
+		return errors.New("checks: invalid profile - missing id")
+	case row.Name == "":
+		return errors.New("checks: invalid profile - missing name")
+	case row.Realm == "" || row.RealmID <= 0:
+		return errors.New("checks: invalid profile - missing realm or realm id")
+	case row.Class == "" || row.ClassID <= 0:
+		return errors.New("checks: invalid profile - missing class or class id")
+	case row.Spec == "" || row.SpecID <= 0:
+		return errors.New("checks: invalid profile - missing spec or spec id")
+	case row.Level < 0 || row.AverageItemLevel < 0 || row.EquippedItemLevel < 0:
+		return errors.New("checks: invalid profile - negative level or item level")
+	case row.Rating < 0 || row.Rank < 0 || row.Played < 0 || row.Won < 0 || row.Lost < 0:
+		return errors.New("checks: invalid profile - negative ladder value")
+	case row.Won+row.Lost > row.Played:
+		return errors.New("checks: invalid profile - more wins and losses than games played")
+	case row.RosterRank < 0 || row.GuildRegionRank < 0:
+		return errors.New("checks: invalid profile - negative guild rank")
+	}
+	return nil
+}
+
+// MatchBracket check a pvp bracket name against a list of filters and return true if any match or if the list is empty
+// A filter match a bracket by its full name or by its family, ie: shuffle match shuffle-deathknight-blood
+func MatchBracket(bracket string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if bracket == filter || strings.HasPrefix(bracket, filter+"-") {
+			return true
+		}
+	}
+	return false
+}
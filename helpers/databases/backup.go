@@ -0,0 +1,177 @@
+package databases
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+	"wowstatistician/models"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+)
+
+// statsSource is the source name selecting stats in backups
+const statsSource = "stats"
+
+// BackupDb write a backup of the badger store of a spec to path, restricted to a source when provided, along its manifest
+// Only versions newer than since are backed up, since being the version of a previous backup manifest for an incremental backup, 0 for a full one
+func BackupDb(spec string, source string, since uint64, path string) (*models.BackupManifest, error) {
+	badgerStore, err := openBadgerSpec(spec)
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	defer badgerStore.Close()
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	defer file.Close()
+	stream := badgerStore.DB().NewStream()
+	stream.LogPrefix = "databases: backup"
+	if source != "" {
+		stream.ChooseKey = func(item *badger.Item) bool {
+			return keySource(item.Key()) == source
+		}
+	}
+	version, err := stream.Backup(file, since)
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	err = file.Close()
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	manifest, err := inspectBackup(path)
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	manifest.Source = source
+	manifest.Since = since
+	manifest.Version = version
+	manifest.CreatedAt = time.Now().Unix()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	err = ioutil.WriteFile(manifestPath(path), data, 0644)
+	if err != nil {
+		return nil, errors.New("databases: could not backup db - " + err.Error())
+	}
+	return manifest, nil
+}
+
+// RestoreDb load a backup file written by BackupDb into the badger store of a spec and return its manifest
+// The backup is checked against its manifest when one is found next to it, and refused if its checksum do not match
+func RestoreDb(spec string, path string) (*models.BackupManifest, error) {
+	manifest, err := inspectBackup(path)
+	if err != nil {
+		return nil, errors.New("databases: could not restore db - " + err.Error())
+	}
+	data, err := ioutil.ReadFile(manifestPath(path))
+	if err == nil {
+		var expected models.BackupManifest
+		err = json.Unmarshal(data, &expected)
+		if err != nil {
+			return nil, errors.New("databases: could not restore db - invalid manifest: " + err.Error())
+		}
+		if expected.SHA256 != manifest.SHA256 || expected.Records != manifest.Records {
+			return nil, errors.New("databases: could not restore db - backup do not match its manifest, expected sha256 " + expected.SHA256 + " got " + manifest.SHA256)
+		}
+		manifest = &expected
+	} else if !os.IsNotExist(err) {
+		return nil, errors.New("databases: could not restore db - " + err.Error())
+	}
+	badgerStore, err := openBadgerSpec(spec)
+	if err != nil {
+		return nil, errors.New("databases: could not restore db - " + err.Error())
+	}
+	defer badgerStore.Close()
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("databases: could not restore db - " + err.Error())
+	}
+	defer file.Close()
+	err = badgerStore.DB().Load(file, 256)
+	if err != nil {
+		return nil, errors.New("databases: could not restore db - " + err.Error())
+	}
+	return manifest, nil
+}
+
+// openBadgerSpec open the badger store of a spec with an empty scope, failing for other kinds of stores
+func openBadgerSpec(spec string) (*BadgerStore, error) {
+	kind, path, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if kind != "badger" {
+		return nil, errors.New("only badger stores support backups, got: " + kind)
+	}
+	return OpenBadgerStore(path, Scope{})
+}
+
+// inspectBackup read a backup file and return a manifest holding its record count, size and checksum
+func inspectBackup(path string) (*models.BackupManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	reader := bufio.NewReader(io.TeeReader(file, hash))
+	manifest := &models.BackupManifest{}
+	for {
+		var size uint64
+		err := binary.Read(reader, binary.LittleEndian, &size)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("corrupted backup - " + err.Error())
+		}
+		data := make([]byte, size)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, errors.New("corrupted backup - " + err.Error())
+		}
+		list := &pb.KVList{}
+		err = list.Unmarshal(data)
+		if err != nil {
+			return nil, errors.New("corrupted backup - " + err.Error())
+		}
+		manifest.Records += len(list.Kv)
+		manifest.Bytes += int64(8 + size)
+	}
+	manifest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return manifest, nil
+}
+
+// manifestPath return the path of the manifest of a backup file
+func manifestPath(path string) string {
+	return path + ".manifest.json"
+}
+
+// keySource return the source a key belongs to, stats for stats keys and empty for store metadata
+func keySource(key []byte) string {
+	parts := strings.Split(string(key), "/")
+	switch {
+	case parts[0]+"/" == statsPrefix:
+		return statsSource
+	case parts[0]+"/" == indexPrefix:
+		return parts[len(parts)-1]
+	case parts[0]+"/" == metaPrefix && len(parts) > 2:
+		// meta/crawl/<source>/... and meta/scope/<source>/...
+		return parts[2]
+	case len(parts) > 1 && parts[0]+"/" != metaPrefix:
+		return parts[1]
+	}
+	return ""
+}
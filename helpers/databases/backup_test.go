@@ -0,0 +1,119 @@
+package databases
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wowstatistician/models"
+)
+
+// countProfiles return the number of profiles of a source in the eu region of a store spec
+func countProfiles(t *testing.T, spec string, source string) int {
+	store, err := OpenStore(spec, Scope{Source: source, Region: "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	profiles := 0
+	err = store.Entries("", func(entry Entry) error {
+		profiles++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profiles
+}
+
+func TestBackupRestore(t *testing.T) {
+	spec := testSpec(t, "badger")
+	for source, IDs := range map[string][]int{"arena": {1, 2}, "raid": {3}} {
+		store, err := OpenStore(spec, Scope{Source: source, Region: "eu"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ID := range IDs {
+			err = store.WriteProfile("", testProfile(ID, "Mage", "Fire"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		store.Close()
+	}
+	_, dbpath, _ := parseSpec(testSpec(t, "badger"))
+	dir := filepath.Dir(dbpath)
+	tests := []struct {
+		name   string
+		source string
+		want   map[string]int
+	}{
+		{name: "whole store", want: map[string]int{"arena": 2, "raid": 1}},
+		{name: "single source", source: "raid", want: map[string]int{"arena": 0, "raid": 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name+".bak")
+			manifest, err := BackupDb(spec, test.source, 0, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.Records == 0 || manifest.Version == 0 {
+				t.Errorf("manifest = %+v, want records and a version", manifest)
+			}
+			restored := testSpec(t, "badger")
+			_, err = RestoreDb(restored, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for source, want := range test.want {
+				if profiles := countProfiles(t, restored, source); profiles != want {
+					t.Errorf("restored %v profiles = %v, want %v", source, profiles, want)
+				}
+			}
+		})
+	}
+	// An incremental backup only hold the versions written since the previous backup
+	path := filepath.Join(dir, "full.bak")
+	full, err := BackupDb(spec, "", 0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenStore(spec, Scope{Source: "arena", Region: "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.WriteProfile("", testProfile(4, "Mage", "Fire"))
+	store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	incremental, err := BackupDb(spec, "", full.Version, filepath.Join(dir, "incremental.bak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incremental.Records == 0 || incremental.Records >= full.Records {
+		t.Errorf("incremental records = %v, want fewer than the %v of the full backup", incremental.Records, full.Records)
+	}
+	// A backup that do not match its manifest is refused
+	var manifest models.BackupManifest
+	data, err := ioutil.ReadFile(manifestPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.SHA256 = strings.Repeat("0", len(manifest.SHA256))
+	data, _ = json.Marshal(manifest)
+	err = ioutil.WriteFile(manifestPath(path), data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = RestoreDb(testSpec(t, "badger"), path)
+	if err == nil {
+		t.Error("restored a backup that do not match its manifest")
+	}
+}
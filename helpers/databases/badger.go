@@ -0,0 +1,1138 @@
+package databases
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"wowstatistician/characters"
+	"wowstatistician/helpers"
+	"wowstatistician/models"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+const (
+	profilePrefix    = "profile/"
+	ladderPrefix     = "ladder/"
+	guildPrefix      = "guild/"
+	memberPrefix     = "member/"
+	runPrefix        = "run/"
+	historyPrefix    = "history/"
+	provenancePrefix = "provenance/"
+	crawlPrefix      = "meta/crawl/"
+	cutoffPrefix     = "meta/cutoff/"
+	indexPrefix      = "index/"
+	secondaryPrefix  = "by/"
+	statsPrefix      = "stats/"
+	metaPrefix       = "meta/"
+)
+
+// LayoutVersion is the version of the key layout of a badger store, kept under the meta/layout key
+//
+// Every source and region share a single badger db, their keys being namespaced as <kind>/<source>/<region>/...
+// Guilds are keyed by the raid hall of fame they were listed by as guild/<source>/<region>/<raid>/<id>
+// Characters are indexed across sources as index/<region>/<id>/<source>
+// Profiles are indexed by class, spec, realm and faction as by/<source>/<region>/<field>/<value>/[<bracket>/]<id>
+// Stats are shared by every source as stats/<name>/<snapshot> and store metadata is kept under meta/
+const LayoutVersion = 1
+
+var layoutKey = []byte(metaPrefix + "layout")
+
+// Scope namespace the profiles, collection context and runs of a store by source - ie: raid, mythic, arena or rbg - and region
+type Scope struct {
+	Source string
+	Region string
+}
+
+func (s Scope) prefix(kind string) string {
+	return kind + s.Source + "/" + s.Region + "/"
+}
+
+// ProfileKey return the key a character profile is stored under, tagged by pvp bracket when provided
+func (s Scope) ProfileKey(bracket string, ID int) []byte {
+	return []byte(s.ProfilePrefix(bracket) + strconv.Itoa(ID))
+}
+
+// ProfilePrefix return the key prefix shared by every profile of a pvp bracket, or by every profile of the scope if bracket is empty
+func (s Scope) ProfilePrefix(bracket string) string {
+	if bracket == "" {
+		return s.prefix(profilePrefix)
+	}
+	return s.prefix(profilePrefix) + bracket + "/"
+}
+
+// LadderKey return the key a pvp ladder entry is stored under
+func (s Scope) LadderKey(bracket string, ID int) []byte {
+	return []byte(s.prefix(ladderPrefix) + bracket + "/" + strconv.Itoa(ID))
+}
+
+// GuildKey return the key the hall of fame entry of a guild for a raid is stored under
+func (s Scope) GuildKey(raid string, ID int) []byte {
+	return []byte(s.prefix(guildPrefix) + guildRef(raid, ID))
+}
+
+// MemberKey return the key the guild membership of a character is stored under
+func (s Scope) MemberKey(ID int) []byte {
+	return []byte(s.prefix(memberPrefix) + strconv.Itoa(ID))
+}
+
+// RunKey return the key a mythic+ run is stored under
+func (s Scope) RunKey(ID string) []byte {
+	return []byte(s.prefix(runPrefix) + ID)
+}
+
+// ProvenanceKey return the key the provenance of a character profile is stored under, tagged by pvp bracket when provided
+func (s Scope) ProvenanceKey(bracket string, ID int) []byte {
+	if bracket == "" {
+		return []byte(s.prefix(provenancePrefix) + strconv.Itoa(ID))
+	}
+	return []byte(s.prefix(provenancePrefix) + bracket + "/" + strconv.Itoa(ID))
+}
+
+// CrawlKey return the key the latest complete crawl of a pvp bracket, empty if none, is stored under
+func (s Scope) CrawlKey(bracket string) []byte {
+	return []byte(s.prefix(crawlPrefix) + bracket)
+}
+
+// CutoffKey return the key the rank 1 rating cutoff of a pvp bracket is stored under
+func (s Scope) CutoffKey(bracket string) []byte {
+	return []byte(s.prefix(cutoffPrefix) + bracket)
+}
+
+// IndexKey return the key recording that the scope source hold a profile of the character of provided ID
+func (s Scope) IndexKey(ID int) []byte {
+	return []byte(s.characterIndexPrefix(ID) + s.Source)
+}
+
+// characterIndexPrefix return the key prefix shared by the index keys of every source holding the character of provided ID in the scope region
+func (s Scope) characterIndexPrefix(ID int) string {
+	return indexPrefix + s.Region + "/" + strconv.Itoa(ID) + "/"
+}
+
+// SecondaryKey return the key indexing a character profile, tagged by pvp bracket when provided, by the value of one of its fields
+func (s Scope) SecondaryKey(field string, value string, bracket string, ID int) []byte {
+	return []byte(s.secondaryPrefix(field, value) + strings.TrimPrefix(string(s.ProfileKey(bracket, ID)), s.ProfilePrefix("")))
+}
+
+// secondaryPrefix return the key prefix shared by the index keys of every profile with a value of a field
+func (s Scope) secondaryPrefix(field string, value string) string {
+	return s.prefix(secondaryPrefix) + field + "/" + value + "/"
+}
+
+// ProfileSnapshotKey return the key the snapshot copy of a character profile is stored under, tagged by pvp bracket when provided
+func (s Scope) ProfileSnapshotKey(bracket string, ID int, snapshot int64) []byte {
+	return []byte(s.profileSnapshotPrefix(bracket, ID) + snapshotSuffix(snapshot))
+}
+
+func (s Scope) profileSnapshotPrefix(bracket string, ID int) string {
+	if bracket == "" {
+		return s.prefix(historyPrefix) + strconv.Itoa(ID) + "/"
+	}
+	return s.prefix(historyPrefix) + bracket + "/" + strconv.Itoa(ID) + "/"
+}
+
+// MetaKey return the key recording that a store has been opened for the scope
+func (s Scope) MetaKey() []byte {
+	return []byte(s.prefix(metaPrefix + "scope/"))
+}
+
+// StatsKey return the key a stats snapshot is stored under
+func StatsKey(name string, snapshot int64) []byte {
+	return []byte(statsSnapshotPrefix(name) + snapshotSuffix(snapshot))
+}
+
+func statsSnapshotPrefix(name string) string {
+	return statsPrefix + name + "/"
+}
+
+// snapshotSuffix zero pad a snapshot so keys sort by time
+func snapshotSuffix(snapshot int64) string {
+	return fmt.Sprintf("%020d", snapshot)
+}
+
+// BadgerStore is a Store backed by a badger db, scoped to a source and region so every source can share the same db
+type BadgerStore struct {
+	db    *badger.DB
+	scope Scope
+	// view is true for stores sharing the db of another store, which close it
+	view bool
+}
+
+// OpenBadgerStore open a badger backed store for provided scope at provided path, an empty source giving access to stats only
+// The layout version of the db is checked, an empty db being stamped with the current one, and the scope is recorded in the db metadata
+func OpenBadgerStore(path string, scope Scope) (*BadgerStore, error) {
+	db, err := OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &BadgerStore{db: db, scope: scope}
+	err = store.checkLayout()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if scope.Source != "" && scope.Region != "" {
+		err = store.set(scope.MetaKey(), nil)
+		if err != nil {
+			db.Close()
+			return nil, errors.New("databases: could not open db - " + err.Error())
+		}
+	}
+	return store, nil
+}
+
+// OpenBadgerStoreReadOnly open a badger backed store at provided path for reading only, ie: to serve its stats while other commands write to it
+// Writes to the returned store fail, and so does opening it while another process holds the db for writing
+func OpenBadgerStoreReadOnly(path string) (*BadgerStore, error) {
+	db, err := openDB(path, true)
+	if err != nil {
+		return nil, err
+	}
+	store := &BadgerStore{db: db}
+	data, err := store.get(layoutKey)
+	if err != nil && err != badger.ErrKeyNotFound {
+		db.Close()
+		return nil, errors.New("databases: could not read db layout - " + err.Error())
+	}
+	if err == nil && string(data) != strconv.Itoa(LayoutVersion) {
+		db.Close()
+		return nil, errors.New("databases: could not open db - unknown layout version: " + string(data))
+	}
+	return store, nil
+}
+
+// OpenDB open a db at provided path and return a db pointer
+func OpenDB(path string) (*badger.DB, error) {
+	return openDB(path, false)
+}
+
+// openDB open a db at provided path, for reading only when readOnly is true
+func openDB(path string, readOnly bool) (*badger.DB, error) {
+	options := badger.DefaultOptions(path)
+	options.Logger = nil
+	options.Truncate = true
+	options.ReadOnly = readOnly
+	db, err := badger.Open(options)
+	if err != nil {
+		return nil, errors.New("databases: could not open db - " + err.Error())
+	}
+	return db, nil
+}
+
+// checkLayout stamp an empty db with the current layout version or fail on a db of another version
+func (s *BadgerStore) checkLayout() error {
+	data, err := s.get(layoutKey)
+	if err == badger.ErrKeyNotFound {
+		return s.set(layoutKey, []byte(strconv.Itoa(LayoutVersion)))
+	}
+	if err != nil {
+		return errors.New("databases: could not read db layout - " + err.Error())
+	}
+	if string(data) != strconv.Itoa(LayoutVersion) {
+		return errors.New("databases: could not open db - unknown layout version: " + string(data))
+	}
+	return nil
+}
+
+// Scopes list every source and region a store has been opened for
+func (s *BadgerStore) Scopes() ([]Scope, error) {
+	scopes := []Scope{}
+	prefix := []byte(metaPrefix + "scope/")
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = prefix
+		options.PrefetchValues = false
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			parts := strings.Split(strings.TrimSuffix(string(bytes.TrimPrefix(iterator.Item().Key(), prefix)), "/"), "/")
+			if len(parts) == 2 {
+				scopes = append(scopes, Scope{Source: parts[0], Region: parts[1]})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not list db scopes - " + err.Error())
+	}
+	return scopes, nil
+}
+
+// DB return the underlying badger db
+func (s *BadgerStore) DB() *badger.DB {
+	return s.db
+}
+
+// Close close the underlying badger db, unless the store is a view of another store
+func (s *BadgerStore) Close() error {
+	if s.view {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Regions list the regions a store has been opened for with the source of the store
+func (s *BadgerStore) Regions() ([]string, error) {
+	scopes, err := s.Scopes()
+	if err != nil {
+		return nil, err
+	}
+	regions := []string{}
+	for _, scope := range scopes {
+		if scope.Source == s.scope.Source {
+			regions = append(regions, scope.Region)
+		}
+	}
+	return regions, nil
+}
+
+// WithRegion return a view of the store scoped to another region of its source, sharing and not closing the underlying db
+func (s *BadgerStore) WithRegion(region string) Store {
+	return &BadgerStore{db: s.db, scope: Scope{Source: s.scope.Source, Region: region}, view: true}
+}
+
+// Scope return the source and region the store is scoped to
+func (s *BadgerStore) Scope() Scope {
+	return s.scope
+}
+
+// Sources list the sources holding a profile of the character of provided ID in the store region, sorted
+func (s *BadgerStore) Sources(ID int) ([]string, error) {
+	sources := []string{}
+	prefix := []byte(s.scope.characterIndexPrefix(ID))
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = prefix
+		options.PrefetchValues = false
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			sources = append(sources, string(bytes.TrimPrefix(iterator.Item().Key(), prefix)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not list character sources from db - " + err.Error())
+	}
+	return sources, nil
+}
+
+// WithSource return a view of the store scoped to another source of its region, sharing and not closing the underlying db
+func (s *BadgerStore) WithSource(source string) Store {
+	return &BadgerStore{db: s.db, scope: Scope{Source: source, Region: s.scope.Region}, view: true}
+}
+
+// WriteProfile write a character profile, tagged by pvp bracket when provided, index it across sources and by class, spec, realm and faction
+func (s *BadgerStore) WriteProfile(bracket string, characterProfile characters.CharacterProfile) error {
+	data, err := helpers.EncodeProfile(characterProfile)
+	if err != nil {
+		return errors.New("databases: could not write profile to db - " + err.Error())
+	}
+	err = s.db.Update(func(txn *badger.Txn) error {
+		key := s.scope.ProfileKey(bracket, characterProfile.ID)
+		values := indexValues(&characterProfile)
+		item, err := txn.Get(key)
+		if err == nil {
+			err = item.Value(func(previous []byte) error {
+				previousProfile, err := helpers.DecodeProfile(previous)
+				if err != nil {
+					// An undecodable profile is overwritten, its stale index keys being skipped by queries
+					return nil
+				}
+				for field, value := range indexValues(previousProfile) {
+					if value != "" && value != values[field] {
+						err := txn.Delete(s.scope.SecondaryKey(field, value, bracket, characterProfile.ID))
+						if err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+		}
+		if err != nil && err != badger.ErrKeyNotFound {
+			return err
+		}
+		err = txn.Set(key, data)
+		if err != nil {
+			return err
+		}
+		for field, value := range values {
+			if value != "" {
+				err = txn.Set(s.scope.SecondaryKey(field, value, bracket, characterProfile.ID), nil)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return txn.Set(s.scope.IndexKey(characterProfile.ID), nil)
+	})
+	if err != nil {
+		return errors.New("databases: could not write profile to db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadProfile read a character profile provided its pvp bracket, empty if none, and ID
+func (s *BadgerStore) ReadProfile(bracket string, ID int) (*characters.CharacterProfile, error) {
+	data, err := s.get(s.scope.ProfileKey(bracket, ID))
+	if err != nil {
+		return nil, errors.New("databases: could not read profile from db - " + err.Error())
+	}
+	characterProfile, err := helpers.DecodeProfile(data)
+	if err != nil {
+		return nil, errors.New("databases: could not read profile from db - " + err.Error())
+	}
+	return characterProfile, nil
+}
+
+// WriteLadder write the pvp ladder entry a profile was collected from
+func (s *BadgerStore) WriteLadder(ladder models.Ladder) error {
+	data, err := helpers.EncodeLadder(ladder)
+	if err != nil {
+		return errors.New("databases: could not write ladder to db - " + err.Error())
+	}
+	err = s.set(s.scope.LadderKey(ladder.Bracket, ladder.ID), data)
+	if err != nil {
+		return errors.New("databases: could not write ladder to db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadLadder read a pvp ladder entry provided its bracket and character ID
+func (s *BadgerStore) ReadLadder(bracket string, ID int) (*models.Ladder, error) {
+	data, err := s.get(s.scope.LadderKey(bracket, ID))
+	if err != nil {
+		return nil, errors.New("databases: could not read ladder from db - " + err.Error())
+	}
+	ladder, err := helpers.DecodeLadder(data)
+	if err != nil {
+		return nil, errors.New("databases: could not read ladder from db - " + err.Error())
+	}
+	return ladder, nil
+}
+
+// WriteGuild write a raid hall of fame guild
+func (s *BadgerStore) WriteGuild(guild models.Guild) error {
+	data, err := helpers.EncodeGuild(guild)
+	if err != nil {
+		return errors.New("databases: could not write guild to db - " + err.Error())
+	}
+	err = s.set(s.scope.GuildKey(guild.Raid, guild.ID), data)
+	if err != nil {
+		return errors.New("databases: could not write guild to db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadGuild read a raid hall of fame guild provided its raid and ID
+func (s *BadgerStore) ReadGuild(raid string, ID int) (*models.Guild, error) {
+	data, err := s.get(s.scope.GuildKey(raid, ID))
+	if err != nil {
+		return nil, errors.New("databases: could not read guild from db - " + err.Error())
+	}
+	guild, err := helpers.DecodeGuild(data)
+	if err != nil {
+		return nil, errors.New("databases: could not read guild from db - " + err.Error())
+	}
+	return guild, nil
+}
+
+// WriteMember write the guild membership of a character
+func (s *BadgerStore) WriteMember(member models.Member) error {
+	data, err := helpers.EncodeMember(member)
+	if err != nil {
+		return errors.New("databases: could not write member to db - " + err.Error())
+	}
+	err = s.set(s.scope.MemberKey(member.ID), data)
+	if err != nil {
+		return errors.New("databases: could not write member to db - " + err.Error())
+	}
+	return nil
+}
+
+// WriteRun write a mythic+ run
+func (s *BadgerStore) WriteRun(run models.Run) error {
+	data, err := helpers.EncodeRun(run)
+	if err != nil {
+		return errors.New("databases: could not write run to db - " + err.Error())
+	}
+	err = s.set(s.scope.RunKey(run.ID), data)
+	if err != nil {
+		return errors.New("databases: could not write run to db - " + err.Error())
+	}
+	return nil
+}
+
+// WriteProvenance write where a profile was collected from and when it was fetched
+func (s *BadgerStore) WriteProvenance(provenance models.Provenance) error {
+	data, err := helpers.EncodeProvenance(provenance)
+	if err != nil {
+		return errors.New("databases: could not write provenance to db - " + err.Error())
+	}
+	err = s.set(s.scope.ProvenanceKey(provenance.Bracket, provenance.ID), data)
+	if err != nil {
+		return errors.New("databases: could not write provenance to db - " + err.Error())
+	}
+	return nil
+}
+
+// WriteCrawl record provided crawl as the latest complete crawl of a pvp bracket, empty if none
+func (s *BadgerStore) WriteCrawl(bracket string, crawl int64) error {
+	err := s.set(s.scope.CrawlKey(bracket), []byte(strconv.FormatInt(crawl, 10)))
+	if err != nil {
+		return errors.New("databases: could not write crawl to db - " + err.Error())
+	}
+	return nil
+}
+
+// LatestCrawl return the latest complete crawl of a pvp bracket, empty if none, 0 if no crawl completed
+func (s *BadgerStore) LatestCrawl(bracket string) (int64, error) {
+	data, err := s.get(s.scope.CrawlKey(bracket))
+	if err == badger.ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.New("databases: could not read crawl from db - " + err.Error())
+	}
+	crawl, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, errors.New("databases: could not read crawl from db - " + err.Error())
+	}
+	return crawl, nil
+}
+
+// WriteCutoff record the rank 1 rating cutoff of a pvp bracket, as published with the season rewards
+func (s *BadgerStore) WriteCutoff(bracket string, rating int) error {
+	err := s.set(s.scope.CutoffKey(bracket), []byte(strconv.Itoa(rating)))
+	if err != nil {
+		return errors.New("databases: could not write cutoff to db - " + err.Error())
+	}
+	return nil
+}
+
+// Cutoff return the rank 1 rating cutoff of a pvp bracket, 0 if none was recorded
+func (s *BadgerStore) Cutoff(bracket string) (int, error) {
+	data, err := s.get(s.scope.CutoffKey(bracket))
+	if err == badger.ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.New("databases: could not read cutoff from db - " + err.Error())
+	}
+	rating, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, errors.New("databases: could not read cutoff from db - " + err.Error())
+	}
+	return rating, nil
+}
+
+// WriteProfileSnapshot write a copy of a character profile for provided snapshot, tagged by pvp bracket when provided
+func (s *BadgerStore) WriteProfileSnapshot(snapshot int64, bracket string, characterProfile characters.CharacterProfile) error {
+	data, err := helpers.EncodeProfile(characterProfile)
+	if err != nil {
+		return errors.New("databases: could not write profile snapshot to db - " + err.Error())
+	}
+	err = s.set(s.scope.ProfileSnapshotKey(bracket, characterProfile.ID, snapshot), data)
+	if err != nil {
+		return errors.New("databases: could not write profile snapshot to db - " + err.Error())
+	}
+	return nil
+}
+
+// ProfileSnapshots read every snapshot copy of a character profile provided its pvp bracket, empty if none, and ID, oldest first
+func (s *BadgerStore) ProfileSnapshots(bracket string, ID int) ([]ProfileSnapshot, error) {
+	snapshots := []ProfileSnapshot{}
+	prefix := []byte(s.scope.profileSnapshotPrefix(bracket, ID))
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = prefix
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			snapshot, err := strconv.ParseInt(string(bytes.TrimPrefix(item.Key(), prefix)), 10, 64)
+			if err != nil {
+				return err
+			}
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			characterProfile, err := helpers.DecodeProfile(data)
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, ProfileSnapshot{Snapshot: snapshot, Profile: characterProfile})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not read profile snapshots from db - " + err.Error())
+	}
+	return snapshots, nil
+}
+
+// WriteStats append stats under provided name, as the snapshot they carry or as a new one if they carry none
+func (s *BadgerStore) WriteStats(name string, stats models.Stats) error {
+	if stats.Snapshot == 0 {
+		stats.Snapshot = NewSnapshot()
+	}
+	data, err := helpers.EncodeStats(stats)
+	if err != nil {
+		return errors.New("databases: could not write stats to db - " + err.Error())
+	}
+	err = s.db.Update(func(txn *badger.Txn) error {
+		_, err := txn.Get(StatsKey(name, stats.Snapshot))
+		if err == nil {
+			return errSnapshotExists(name, stats.Snapshot)
+		}
+		if err != badger.ErrKeyNotFound {
+			return err
+		}
+		return txn.Set(StatsKey(name, stats.Snapshot), data)
+	})
+	if err != nil {
+		return errors.New("databases: could not write stats to db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadStats read the latest stats snapshot provided their name
+func (s *BadgerStore) ReadStats(name string) (*models.Stats, error) {
+	var data []byte
+	err := s.db.View(func(tnx *badger.Txn) error {
+		prefix := []byte(statsSnapshotPrefix(name))
+		options := badger.DefaultIteratorOptions
+		options.Prefix = prefix
+		options.Reverse = true
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		iterator.Seek(append(prefix, 0xFF))
+		if !iterator.Valid() {
+			return badger.ErrKeyNotFound
+		}
+		var err error
+		data, err = iterator.Item().ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not read stats from db - " + err.Error())
+	}
+	stats, err := helpers.DecodeStats(data)
+	if err != nil {
+		return nil, errors.New("databases: could not read stats from db - " + err.Error())
+	}
+	return stats, nil
+}
+
+// ReadSnapshot read a stats snapshot provided their name and snapshot
+func (s *BadgerStore) ReadSnapshot(name string, snapshot int64) (*models.Stats, error) {
+	data, err := s.get(StatsKey(name, snapshot))
+	if err != nil {
+		return nil, errors.New("databases: could not read stats snapshot from db - " + err.Error())
+	}
+	stats, err := helpers.DecodeStats(data)
+	if err != nil {
+		return nil, errors.New("databases: could not read stats snapshot from db - " + err.Error())
+	}
+	return stats, nil
+}
+
+// Snapshots list the stats snapshots of provided name, oldest first
+func (s *BadgerStore) Snapshots(name string) ([]int64, error) {
+	snapshots := []int64{}
+	prefix := []byte(statsSnapshotPrefix(name))
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = prefix
+		options.PrefetchValues = false
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			snapshot, err := strconv.ParseInt(string(bytes.TrimPrefix(iterator.Item().Key(), prefix)), 10, 64)
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not list stats snapshots from db - " + err.Error())
+	}
+	return snapshots, nil
+}
+
+// StatsNames list the names stats have been written under, sorted
+func (s *BadgerStore) StatsNames() ([]string, error) {
+	names := []string{}
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = []byte(statsPrefix)
+		options.PrefetchValues = false
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			key := string(bytes.TrimPrefix(iterator.Item().Key(), []byte(statsPrefix)))
+			i := strings.LastIndex(key, "/")
+			if i < 0 {
+				continue
+			}
+			if name := key[:i]; len(names) == 0 || names[len(names)-1] != name {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not list stats names from db - " + err.Error())
+	}
+	return names, nil
+}
+
+// Entries call fn for every profile with its collection context, restricted to a pvp bracket or bracket family when provided
+// Profiles or context that could not be decoded are logged and skipped
+func (s *BadgerStore) Entries(bracket string, fn func(entry Entry) error) error {
+	err := s.db.View(func(tnx *badger.Txn) error {
+		guilds, err := s.readGuilds(tnx)
+		if err != nil {
+			return err
+		}
+		prefix := s.scope.prefix(profilePrefix)
+		options := badger.DefaultIteratorOptions
+		// A bracket prefix without its trailing slash also cover the brackets of its family, ie: shuffle-deathknight-blood for shuffle
+		options.Prefix = []byte(prefix + bracket)
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			if !matchKeyBracket(strings.TrimPrefix(string(item.Key()), prefix), bracket) {
+				continue
+			}
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			characterProfile, err := helpers.DecodeProfile(data)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			entry, err := s.readEntry(tnx, item.Key(), characterProfile, guilds)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			err = fn(*entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("databases: could not iterate profiles from db - " + err.Error())
+	}
+	return nil
+}
+
+// matchKeyBracket return true if a [<bracket>/]<id> key suffix is of provided bracket or bracket family, any suffix matching an empty bracket
+func matchKeyBracket(suffix string, bracket string) bool {
+	if bracket == "" {
+		return true
+	}
+	keyBracket := ""
+	if i := strings.LastIndex(suffix, "/"); i >= 0 {
+		keyBracket = suffix[:i]
+	}
+	return helpers.MatchBracket(keyBracket, []string{bracket})
+}
+
+// Query call fn for every profile matching query with its collection context, restricted to a pvp bracket or bracket family when provided
+// Only the profiles indexed by the most selective field of the query are read, profiles that could not be decoded being logged and skipped
+func (s *BadgerStore) Query(bracket string, query Query, fn func(entry Entry) error) error {
+	if query.Empty() {
+		return s.Entries(bracket, fn)
+	}
+	field, value := query.indexed()
+	prefix := []byte(s.scope.secondaryPrefix(field, value))
+	err := s.db.View(func(tnx *badger.Txn) error {
+		guilds, err := s.readGuilds(tnx)
+		if err != nil {
+			return err
+		}
+		options := badger.DefaultIteratorOptions
+		options.Prefix = prefix
+		options.PrefetchValues = false
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			suffix := string(bytes.TrimPrefix(iterator.Item().Key(), prefix))
+			if !matchKeyBracket(suffix, bracket) {
+				continue
+			}
+			key := []byte(s.scope.ProfilePrefix("") + suffix)
+			item, err := tnx.Get(key)
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			characterProfile, err := helpers.DecodeProfile(data)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			// Index keys of overwritten profiles may be stale
+			if !query.Match(characterProfile) {
+				continue
+			}
+			entry, err := s.readEntry(tnx, key, characterProfile, guilds)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			err = fn(*entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("databases: could not query profiles from db - " + err.Error())
+	}
+	return nil
+}
+
+// readEntry read the collection context of a profile stored under provided key
+func (s *BadgerStore) readEntry(tnx *badger.Txn, key []byte, characterProfile *characters.CharacterProfile, guilds map[string]*models.Guild) (*Entry, error) {
+	var err error
+	entry := &Entry{
+		Profile: characterProfile,
+	}
+	entry.Ladder, err = s.readLadder(tnx, key)
+	if err != nil {
+		return nil, err
+	}
+	entry.Member, err = s.readMember(tnx, characterProfile.ID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Member != nil {
+		entry.Guild = memberGuild(guilds, entry.Member)
+	}
+	entry.Provenance, err = s.readProvenance(tnx, key)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Runs call fn for every mythic+ run
+// Runs that could not be decoded are logged and skipped
+func (s *BadgerStore) Runs(fn func(run models.Run) error) error {
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = []byte(s.scope.prefix(runPrefix))
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			data, err := iterator.Item().ValueCopy(nil)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			run, err := helpers.DecodeRun(data)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			err = fn(*run)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("databases: could not iterate runs from db - " + err.Error())
+	}
+	return nil
+}
+
+// Migrate rewrite every profile, of every scope, not encoded with the current record version, and index profiles missing from the cross source or secondary indexes
+// Guilds stored before being keyed by raid are moved under their raid and their members linked to it
+// It return the number of keys rewritten or added, profiles that could not be decoded are logged and left untouched
+func (s *BadgerStore) Migrate() (int, error) {
+	rewrites, deletes, err := s.migrateGuilds()
+	if err != nil {
+		return 0, errors.New("databases: could not migrate db - " + err.Error())
+	}
+	err = s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = []byte(profilePrefix)
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			for _, indexKey := range profileIndexKeys(item.Key(), data) {
+				_, err := tnx.Get(indexKey)
+				if err == badger.ErrKeyNotFound {
+					rewrites[string(indexKey)] = nil
+				}
+			}
+			if helpers.ProfileDataVersion(data) == helpers.ProfileVersion {
+				continue
+			}
+			data, err = reencodeProfile(data)
+			if err != nil {
+				log.Println("databases: could not migrate profile " + string(item.Key()) + " - " + err.Error())
+				continue
+			}
+			rewrites[string(item.Key())] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.New("databases: could not migrate db - " + err.Error())
+	}
+	batch := s.db.NewWriteBatch()
+	defer batch.Cancel()
+	for _, key := range deletes {
+		err := batch.Delete([]byte(key))
+		if err != nil {
+			return 0, errors.New("databases: could not migrate db - " + err.Error())
+		}
+	}
+	for key, data := range rewrites {
+		err := batch.Set([]byte(key), data)
+		if err != nil {
+			return 0, errors.New("databases: could not migrate db - " + err.Error())
+		}
+	}
+	err = batch.Flush()
+	if err != nil {
+		return 0, errors.New("databases: could not migrate db - " + err.Error())
+	}
+	return len(rewrites), nil
+}
+
+// migrateGuilds return the keys and data guilds stored under their bare ID, and members recorded without their raid, are rewritten as, and the keys to delete
+func (s *BadgerStore) migrateGuilds() (map[string][]byte, []string, error) {
+	rewrites := map[string][]byte{}
+	deletes := []string{}
+	guilds := map[string]map[string]*models.Guild{}
+	err := s.db.View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = []byte(guildPrefix)
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			parts := strings.Split(string(item.Key()), "/")
+			if len(parts) < 4 {
+				continue
+			}
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			guild, err := helpers.DecodeGuild(data)
+			if err != nil {
+				log.Println("databases: could not migrate guild " + string(item.Key()) + " - " + err.Error())
+				continue
+			}
+			scope := Scope{Source: parts[1], Region: parts[2]}
+			if guilds[scope.prefix("")] == nil {
+				guilds[scope.prefix("")] = map[string]*models.Guild{}
+			}
+			guilds[scope.prefix("")][guildRef(guild.Raid, guild.ID)] = guild
+			if len(parts) == 4 {
+				deletes = append(deletes, string(item.Key()))
+				_, err = tnx.Get(scope.GuildKey(guild.Raid, guild.ID))
+				if err == badger.ErrKeyNotFound {
+					rewrites[string(scope.GuildKey(guild.Raid, guild.ID))] = data
+				}
+			}
+		}
+		options.Prefix = []byte(memberPrefix)
+		members := tnx.NewIterator(options)
+		defer members.Close()
+		for members.Rewind(); members.Valid(); members.Next() {
+			item := members.Item()
+			parts := strings.Split(string(item.Key()), "/")
+			if len(parts) != 4 {
+				continue
+			}
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			member, err := helpers.DecodeMember(data)
+			if err != nil || member.Raid != "" {
+				continue
+			}
+			guild := memberGuild(guilds[Scope{Source: parts[1], Region: parts[2]}.prefix("")], member)
+			if guild == nil {
+				continue
+			}
+			member.Raid = guild.Raid
+			data, err = helpers.EncodeMember(*member)
+			if err != nil {
+				return err
+			}
+			rewrites[string(item.Key())] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rewrites, deletes, nil
+}
+
+// profileIndexKeys return the cross source and secondary index keys of a profile key and its data, none if the key is not a profile key
+// Secondary index keys are left out when the profile could not be decoded
+func profileIndexKeys(key []byte, data []byte) [][]byte {
+	parts := strings.Split(string(key), "/")
+	if len(parts) < 4 || parts[0]+"/" != profilePrefix {
+		return nil
+	}
+	ID, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return nil
+	}
+	scope := Scope{Source: parts[1], Region: parts[2]}
+	bracket := strings.Join(parts[3:len(parts)-1], "/")
+	keys := [][]byte{scope.IndexKey(ID)}
+	characterProfile, err := helpers.DecodeProfile(data)
+	if err != nil {
+		return keys
+	}
+	for field, value := range indexValues(characterProfile) {
+		if value != "" {
+			keys = append(keys, scope.SecondaryKey(field, value, bracket, ID))
+		}
+	}
+	return keys
+}
+
+// reencodeProfile decode a profile of any record version and encode it with the current one
+func reencodeProfile(data []byte) ([]byte, error) {
+	characterProfile, err := helpers.DecodeProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.EncodeProfile(*characterProfile)
+}
+
+func (s *BadgerStore) set(key []byte, data []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, data)
+	})
+}
+
+func (s *BadgerStore) get(key []byte) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		data, err = item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	return data, err
+}
+
+// readLadder return the ladder entry stored next to provided profile key, or nil if the profile does not come from a pvp leatherboard
+func (s *BadgerStore) readLadder(tnx *badger.Txn, profileKey []byte) (*models.Ladder, error) {
+	key := append([]byte(s.scope.prefix(ladderPrefix)), bytes.TrimPrefix(profileKey, []byte(s.scope.prefix(profilePrefix)))...)
+	item, err := tnx.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.DecodeLadder(data)
+}
+
+// readProvenance return the provenance stored next to provided profile key, or nil if none was recorded
+func (s *BadgerStore) readProvenance(tnx *badger.Txn, profileKey []byte) (*models.Provenance, error) {
+	key := append([]byte(s.scope.prefix(provenancePrefix)), bytes.TrimPrefix(profileKey, []byte(s.scope.prefix(profilePrefix)))...)
+	item, err := tnx.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.DecodeProvenance(data)
+}
+
+// readMember return the guild membership of provided character ID, or nil if the profile does not come from a raid guild
+func (s *BadgerStore) readMember(tnx *badger.Txn, ID int) (*models.Member, error) {
+	item, err := tnx.Get(s.scope.MemberKey(ID))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.DecodeMember(data)
+}
+
+// readGuilds return every hall of fame entry of the scope mapped by guild reference
+// Entries are mapped by the raid and ID they hold, so those stored before being keyed by raid are read alike
+func (s *BadgerStore) readGuilds(tnx *badger.Txn) (map[string]*models.Guild, error) {
+	guilds := map[string]*models.Guild{}
+	options := badger.DefaultIteratorOptions
+	options.Prefix = []byte(s.scope.prefix(guildPrefix))
+	iterator := tnx.NewIterator(options)
+	defer iterator.Close()
+	for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+		data, err := iterator.Item().ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		guild, err := helpers.DecodeGuild(data)
+		if err != nil {
+			return nil, err
+		}
+		guilds[guildRef(guild.Raid, guild.ID)] = guild
+	}
+	return guilds, nil
+}
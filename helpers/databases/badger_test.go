@@ -0,0 +1,104 @@
+package databases
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+	"wowstatistician/helpers"
+	"wowstatistician/models"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+func TestMigrateDb(t *testing.T) {
+	spec := testSpec(t, "badger")
+	scope := Scope{Source: "raid", Region: "eu"}
+	store, err := OpenStore(spec, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A gob encoded profile without index keys, a guild stored under its bare ID and a member recorded without its raid
+	var profile bytes.Buffer
+	err = gob.NewEncoder(&profile).Encode(testProfile(1, "Mage", "Fire"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	guild, err := helpers.EncodeGuild(models.Guild{ID: 7, Name: "guild", Raid: "nyalotha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := helpers.EncodeMember(models.Member{ID: 1, GuildID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.(*BadgerStore).DB().Update(func(txn *badger.Txn) error {
+		for key, data := range map[string][]byte{
+			string(scope.ProfileKey("", 1)): profile.Bytes(),
+			scope.prefix(guildPrefix) + "7": guild,
+			string(scope.MemberKey(1)):      member,
+		} {
+			err := txn.Set([]byte(key), data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewritten, err := MigrateDb(spec, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewritten == 0 {
+		t.Error("nothing rewritten by the first migration")
+	}
+	rewritten, err = MigrateDb(spec, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewritten != 0 {
+		t.Errorf("rewritten by a second migration = %v, want 0", rewritten)
+	}
+	store, err = OpenStore(spec, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	data, err := store.(*BadgerStore).get(scope.ProfileKey("", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := helpers.ProfileDataVersion(data); version != helpers.ProfileVersion {
+		t.Errorf("profile version = %v, want %v", version, helpers.ProfileVersion)
+	}
+	_, err = store.(*BadgerStore).get([]byte(scope.prefix(guildPrefix) + "7"))
+	if err == nil {
+		t.Error("guild still stored under its bare ID")
+	}
+	sources, err := store.(Indexer).Sources(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sources, []string{"raid"}) {
+		t.Errorf("sources = %v, want [raid]", sources)
+	}
+	conditions, _ := ParseFilterExpression("spec=fire")
+	found := 0
+	err = QueryEntries(store, "", Filter{Conditions: conditions}.query(), func(entry Entry) error {
+		found++
+		if entry.Guild == nil || entry.Guild.Raid != "nyalotha" {
+			t.Errorf("member guild = %+v, want the nyalotha guild", entry.Guild)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 1 {
+		t.Errorf("profiles found by the spec index = %v, want 1", found)
+	}
+}
@@ -0,0 +1,180 @@
+package databases
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"wowstatistician/models"
+)
+
+// StatsCache serve the stats of a store spec from memory, so the store is only held open while reloading and other commands can write to it meanwhile
+// Only the latest snapshot of every stats name is loaded, older ones being read from the store the first time they are requested
+type StatsCache struct {
+	spec  string
+	mutex sync.RWMutex
+	// reload serialize reloads and publishes, so a reload never drop stats published while it read the store
+	reload    sync.Mutex
+	store     *MemoryStore
+	snapshots map[string][]int64
+}
+
+// NewStatsCache return an empty stats cache of a store spec, filled by Reload
+func NewStatsCache(spec string) *StatsCache {
+	return &StatsCache{
+		spec:      spec,
+		store:     NewMemoryStore(),
+		snapshots: map[string][]int64{},
+	}
+}
+
+// cachedStats is the store view of a stats cache, reading snapshots missing from memory from the store spec
+type cachedStats struct {
+	*MemoryStore
+	cache     *StatsCache
+	snapshots map[string][]int64
+}
+
+// Store return the in memory store holding the cached stats
+func (c *StatsCache) Store() Store {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return &cachedStats{
+		MemoryStore: c.store,
+		cache:       c,
+		snapshots:   c.snapshots,
+	}
+}
+
+// Snapshots list every stats snapshot of provided name as of the latest reload or publish, oldest first
+func (s *cachedStats) Snapshots(name string) ([]int64, error) {
+	return append([]int64{}, s.snapshots[name]...), nil
+}
+
+// ReadSnapshot read a stats snapshot from memory, or from the store spec and keep it in memory until the next reload
+func (s *cachedStats) ReadSnapshot(name string, snapshot int64) (*models.Stats, error) {
+	stats, err := s.MemoryStore.ReadSnapshot(name, snapshot)
+	if err == nil {
+		return stats, nil
+	}
+	source, err := OpenStoreReadOnly(s.cache.spec)
+	if err != nil {
+		return nil, errors.New("databases: could not read cached stats - " + err.Error())
+	}
+	defer source.Close()
+	stats, err = source.ReadSnapshot(name, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	// Another request may have fetched the same snapshot meanwhile, which is fine as snapshots never change
+	s.MemoryStore.WriteStats(name, *stats)
+	return stats, nil
+}
+
+// Reload replace the cached stats by the latest stats snapshot of every name of the store spec
+// The new stats are read without holding the cache lock, so requests are served from the previous ones meanwhile
+func (c *StatsCache) Reload() error {
+	c.reload.Lock()
+	defer c.reload.Unlock()
+	source, err := OpenStoreReadOnly(c.spec)
+	if err != nil {
+		return errors.New("databases: could not reload stats - " + err.Error())
+	}
+	defer source.Close()
+	store := NewMemoryStore()
+	snapshots := map[string][]int64{}
+	names, err := source.StatsNames()
+	if err != nil {
+		return errors.New("databases: could not reload stats - " + err.Error())
+	}
+	for _, name := range names {
+		snapshots[name], err = source.Snapshots(name)
+		if err != nil {
+			return errors.New("databases: could not reload stats - " + err.Error())
+		}
+		if len(snapshots[name]) == 0 {
+			continue
+		}
+		stats, err := source.ReadSnapshot(name, snapshots[name][len(snapshots[name])-1])
+		if err != nil {
+			return errors.New("databases: could not reload stats - " + err.Error())
+		}
+		err = store.WriteStats(name, *stats)
+		if err != nil {
+			return errors.New("databases: could not reload stats - " + err.Error())
+		}
+	}
+	c.mutex.Lock()
+	c.store = store
+	c.snapshots = snapshots
+	c.mutex.Unlock()
+	return nil
+}
+
+// Watch reload the cached stats every interval until stop is closed
+// Failed reloads, ie: while another command holds the store, are logged and the cached stats kept until the next one
+func (c *StatsCache) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := c.Reload()
+			if err != nil {
+				log.Println(err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Publish write freshly computed stats to the store spec under provided name and serve them without waiting for the next reload
+// Stats already written to the store under their snapshot, ie: by a generate command sharing it, are only added to the cache
+func (c *StatsCache) Publish(name string, stats models.Stats) error {
+	if stats.Snapshot == 0 {
+		stats.Snapshot = NewSnapshot()
+	}
+	c.reload.Lock()
+	defer c.reload.Unlock()
+	target, err := OpenStore(c.spec, Scope{})
+	if err != nil {
+		return errors.New("databases: could not publish stats - " + err.Error())
+	}
+	if _, err := target.ReadSnapshot(name, stats.Snapshot); err != nil {
+		err = target.WriteStats(name, stats)
+		if err != nil {
+			target.Close()
+			return errors.New("databases: could not publish stats - " + err.Error())
+		}
+	}
+	err = target.Close()
+	if err != nil {
+		return errors.New("databases: could not publish stats - " + err.Error())
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, err := c.store.ReadSnapshot(name, stats.Snapshot); err == nil {
+		return nil
+	}
+	err = c.store.WriteStats(name, stats)
+	if err != nil {
+		return errors.New("databases: could not publish stats - " + err.Error())
+	}
+	for _, snapshot := range c.snapshots[name] {
+		if snapshot == stats.Snapshot {
+			return nil
+		}
+	}
+	snapshots := map[string][]int64{}
+	for cached, list := range c.snapshots {
+		snapshots[cached] = list
+	}
+	snapshots[name] = append(append([]int64{}, c.snapshots[name]...), stats.Snapshot)
+	sort.Slice(snapshots[name], func(i, j int) bool {
+		return snapshots[name][i] < snapshots[name][j]
+	})
+	c.snapshots = snapshots
+	return nil
+}
@@ -0,0 +1,116 @@
+package databases
+
+import (
+	"reflect"
+	"testing"
+	"wowstatistician/models"
+)
+
+func TestPublishGeneratedStats(t *testing.T) {
+	spec := testSpec(t, "badger")
+	store, err := OpenStore(spec, Scope{Source: "arena", Region: "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.WriteProfile("", testProfile(1, "Mage", "Fire"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	cache := NewStatsCache(spec)
+	err = cache.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := Filter{Region: "eu"}
+	stats, err := WriteStatsForDb(spec, "arena", filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filter.Name("arena")
+	// Publishing stats already written by generate only refresh the cache, publishing them twice is a no-op
+	for i := 0; i < 2; i++ {
+		err = cache.Publish(name, *stats)
+		if err != nil {
+			t.Fatalf("publish %v: %v", i+1, err)
+		}
+	}
+	snapshots, _ := cache.Store().Snapshots(name)
+	if !reflect.DeepEqual(snapshots, []int64{stats.Snapshot}) {
+		t.Errorf("cached snapshots = %v, want %v", snapshots, []int64{stats.Snapshot})
+	}
+	latest, err := cache.Store().ReadStats(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Overall != 1 {
+		t.Errorf("cached overall = %v, want 1", latest.Overall)
+	}
+}
+
+func TestPublishReload(t *testing.T) {
+	spec := testSpec(t, "badger")
+	cache := NewStatsCache(spec)
+	err := cache.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, snapshot := range []int64{100, 200} {
+		err = cache.Publish("raid", models.Stats{Snapshot: snapshot, Overall: int(snapshot)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Published stats are written to the store so a reload keep them, older snapshots being read back on demand
+	err = cache.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, _ := cache.Store().Snapshots("raid")
+	if !reflect.DeepEqual(snapshots, []int64{100, 200}) {
+		t.Errorf("snapshots after reload = %v, want [100 200]", snapshots)
+	}
+	latest, err := cache.Store().ReadStats("raid")
+	if err != nil || latest.Overall != 200 {
+		t.Errorf("latest stats after reload = %v, %v, want overall 200", latest, err)
+	}
+	older, err := cache.Store().ReadSnapshot("raid", 100)
+	if err != nil || older.Overall != 100 {
+		t.Errorf("older snapshot after reload = %v, %v, want overall 100", older, err)
+	}
+}
+
+func TestReloadDuringPublish(t *testing.T) {
+	spec := testSpec(t, "badger")
+	cache := NewStatsCache(spec)
+	err := cache.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		for snapshot := int64(1); snapshot <= 20; snapshot++ {
+			err := cache.Publish("raid", models.Stats{Snapshot: snapshot})
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for i := 0; i < 10; i++ {
+		err = cache.Reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache.Store().ReadStats("raid")
+	}
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, _ := cache.Store().Snapshots("raid")
+	if len(snapshots) != 20 {
+		t.Errorf("cached snapshots = %v, want every published one", snapshots)
+	}
+}
@@ -3,213 +3,584 @@ package databases
 import (
 	"errors"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"wowstatistician/characters"
-	"wowstatistician/helpers"
 	"wowstatistician/models"
-
-	"github.com/dgraph-io/badger/v2"
 )
 
-// WriteProfileToDb write a character profile to a db provided db pointer
-func WriteProfileToDb(db *badger.DB, characterProfile characters.CharacterProfile) error {
-	ID := strconv.Itoa(characterProfile.ID)
-	data, err := helpers.EncodeProfile(characterProfile)
+// ratingBuckets list the ratings players are bucketed at in pvp stats
+var ratingBuckets = []int{1800, 2100, 2400}
+
+// Store abstract where collected profiles, their collection context, mythic+ runs and generated stats are kept
+type Store interface {
+	// WriteProfile write a character profile, tagged by pvp bracket when provided
+	WriteProfile(bracket string, characterProfile characters.CharacterProfile) error
+	// ReadProfile read a character profile provided its pvp bracket, empty if none, and ID
+	ReadProfile(bracket string, ID int) (*characters.CharacterProfile, error)
+	// WriteLadder write the pvp ladder entry a profile was collected from
+	WriteLadder(ladder models.Ladder) error
+	// ReadLadder read a pvp ladder entry provided its bracket and character ID
+	ReadLadder(bracket string, ID int) (*models.Ladder, error)
+	// WriteGuild write a raid hall of fame guild
+	WriteGuild(guild models.Guild) error
+	// ReadGuild read a raid hall of fame guild provided its raid and ID
+	ReadGuild(raid string, ID int) (*models.Guild, error)
+	// WriteMember write the guild membership of a character
+	WriteMember(member models.Member) error
+	// WriteRun write a mythic+ run
+	WriteRun(run models.Run) error
+	// WriteProvenance write where a profile was collected from and when it was fetched
+	WriteProvenance(provenance models.Provenance) error
+	// WriteCrawl record provided crawl as the latest complete crawl of a pvp bracket, or of a raid hall of fame for raid members
+	WriteCrawl(bracket string, crawl int64) error
+	// LatestCrawl return the latest complete crawl of a pvp bracket or raid hall of fame, 0 if no crawl completed
+	LatestCrawl(bracket string) (int64, error)
+	// WriteCutoff record the rank 1 rating cutoff of a pvp bracket, as published with the season rewards
+	WriteCutoff(bracket string, rating int) error
+	// Cutoff return the rank 1 rating cutoff of a pvp bracket, 0 if none was recorded
+	Cutoff(bracket string) (int, error)
+	// Entries call fn for every profile with its collection context, restricted to a pvp bracket or bracket family when provided, ie: 3v3 or shuffle
+	Entries(bracket string, fn func(entry Entry) error) error
+	// Runs call fn for every mythic+ run
+	Runs(fn func(run models.Run) error) error
+	// WriteProfileSnapshot write a copy of a character profile for provided snapshot, tagged by pvp bracket when provided
+	WriteProfileSnapshot(snapshot int64, bracket string, characterProfile characters.CharacterProfile) error
+	// ProfileSnapshots read every snapshot copy of a character profile provided its pvp bracket, empty if none, and ID, oldest first
+	ProfileSnapshots(bracket string, ID int) ([]ProfileSnapshot, error)
+	// WriteStats append stats under provided name, as the snapshot they carry, failing if that snapshot was already written
+	WriteStats(name string, stats models.Stats) error
+	// ReadStats read the latest stats snapshot provided their name
+	ReadStats(name string) (*models.Stats, error)
+	// ReadSnapshot read a stats snapshot provided their name and snapshot
+	ReadSnapshot(name string, snapshot int64) (*models.Stats, error)
+	// Snapshots list the stats snapshots of provided name, oldest first
+	Snapshots(name string) ([]int64, error)
+	// StatsNames list the names stats have been written under
+	StatsNames() ([]string, error)
+	// Close release the store
+	Close() error
+}
+
+// Migrator is implemented by stores keeping encoded profiles, which can be rewritten to the current record version
+type Migrator interface {
+	// Migrate rewrite every profile not encoded with the current record version and return how many were rewritten
+	Migrate() (int, error)
+}
+
+// Regioner is implemented by stores holding several regions of their source
+type Regioner interface {
+	// Regions list the regions of the store source
+	Regions() ([]string, error)
+	// WithRegion return a view of the store scoped to another region of its source, sharing and not closing the underlying db
+	WithRegion(region string) Store
+}
+
+// Indexer is implemented by stores holding several sources, which index their characters across sources by region and ID
+type Indexer interface {
+	// Scope return the source and region the store is scoped to
+	Scope() Scope
+	// Sources list the sources holding a profile of the character of provided ID in the store region, sorted
+	Sources(ID int) ([]string, error)
+	// WithSource return a view of the store scoped to another source of its region, sharing and not closing the underlying db
+	WithSource(source string) Store
+}
+
+// DefaultStore is the store spec used when none is provided, a single badger db in the databases directory
+const DefaultStore = "badger:databases"
+
+// OpenStore open a store scoped to a source - ie: raid, mythic, arena or rbg - and a region provided a store spec, an empty scope giving access to stats only
+// A store spec is either badger:<directory> or sqlite:<file>, every source, region and stats sharing the same db
+func OpenStore(spec string, scope Scope) (Store, error) {
+	kind, path, err := parseSpec(spec)
 	if err != nil {
-		return errors.New("databases: could not write profile to db - " + err.Error())
+		return nil, errors.New("databases: could not open store - " + err.Error())
 	}
-	err = db.Update(func(txn *badger.Txn) error {
-		err := txn.Set([]byte(ID), data)
+	switch kind {
+	case "badger":
+		err := os.MkdirAll(path, 0755)
 		if err != nil {
-			return err
+			return nil, errors.New("databases: could not open store - " + err.Error())
 		}
-		return nil
-	})
-	if err != nil {
-		return errors.New("maidatabasesn: could not write profile to db - " + err.Error())
+		store, err := OpenBadgerStore(path, scope)
+		if err != nil {
+			return nil, err
+		}
+		imported, err := importLegacyDb(store, path, scope.Source)
+		if err != nil {
+			store.Close()
+			return nil, errors.New("databases: could not open store - " + err.Error())
+		}
+		if imported > 0 {
+			log.Printf("[-] Imported %v keys of the legacy db: %v\n", imported, filepath.Join(path, scope.Source))
+		}
+		return store, nil
+	case "sqlite":
+		return OpenSQLiteStore(path, scope)
+	default:
+		return nil, errors.New("databases: could not open store - unknown store kind: " + kind)
 	}
-	return nil
 }
 
-// WriteStatsToDb write stats struct to the stats db provided a dbname to compute stats against
-func WriteStatsToDb(stats models.Stats, dbname string) error {
-	db, err := OpenDB("databases/stats")
+// OpenStoreReadOnly open the store of a spec for reading only, leaving it to other commands to write to it meanwhile
+// A store that does not exist yet is created first, as neither badger nor sqlite can open a missing db for reading only
+func OpenStoreReadOnly(spec string) (Store, error) {
+	kind, path, err := parseSpec(spec)
 	if err != nil {
-		return errors.New("databases: could not write stats for db: " + dbname + " - " + err.Error())
+		return nil, errors.New("databases: could not open store - " + err.Error())
 	}
-	defer db.Close()
-	stats.Source = dbname
-	stats.SyncDate = time.Now().Format("01-02-2006")
-	data, err := helpers.EncodeStats(stats)
-	if err != nil {
-		return errors.New("databases: could not write stats for db: " + dbname + " - " + err.Error())
+	created := path
+	if kind == "badger" {
+		created = filepath.Join(path, "MANIFEST")
 	}
-	err = db.Update(func(txn *badger.Txn) error {
-		err := txn.Set([]byte(dbname), data)
+	if _, err := os.Stat(created); os.IsNotExist(err) {
+		store, err := OpenStore(spec, Scope{})
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return nil
-	})
+		err = store.Close()
+		if err != nil {
+			return nil, errors.New("databases: could not open store - " + err.Error())
+		}
+	}
+	switch kind {
+	case "badger":
+		return OpenBadgerStoreReadOnly(path)
+	case "sqlite":
+		return OpenSQLiteStoreReadOnly(path)
+	default:
+		return nil, errors.New("databases: could not open store - unknown store kind: " + kind)
+	}
+}
+
+// parseSpec split a store spec in its kind and path, DefaultStore being used for an empty spec
+func parseSpec(spec string) (string, string, error) {
+	if spec == "" {
+		spec = DefaultStore
+	}
+	kind, path := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, path = spec[:i], spec[i+1:]
+	}
+	if path == "" {
+		return "", "", errors.New("missing path in store spec: " + spec)
+	}
+	return kind, path, nil
+}
+
+// writeStats append a new snapshot of stats to a store provided the source they were computed against and the filter they were generated with
+func writeStats(store Store, stats *models.Stats, source string, filter Filter) error {
+	stats.Source = source
+	stats.Region = filter.Region
+	stats.Bracket = filter.Bracket
+	stats.MinRating = filter.MinRating
+	stats.Top = filter.Top
+	stats.TopGuilds = filter.TopGuilds
+	stats.RosterRanks = filter.RosterRanks
+	stats.LatestCrawl = filter.LatestCrawl
+	stats.Filter = filter.Expression()
+	stats.Snapshot = NewSnapshot()
+	stats.SyncDate = time.Unix(stats.Snapshot, 0).Format("01-02-2006")
+	return store.WriteStats(filter.Name(source), *stats)
+}
+
+// ReadStatsDb read stats struct from the stats db of a store spec provided a dbname and a snapshot, 0 for the latest
+func ReadStatsDb(spec string, dbname string, snapshot int64) (*models.Stats, error) {
+	store, err := OpenStore(spec, Scope{})
+	if err != nil {
+		return nil, errors.New("databases: could not read stats db - " + err.Error())
+	}
+	defer store.Close()
+	var stats *models.Stats
+	if snapshot == 0 {
+		stats, err = store.ReadStats(dbname)
+	} else {
+		stats, err = store.ReadSnapshot(dbname, snapshot)
+	}
 	if err != nil {
-		return errors.New("databases: could not write stats for db: " + dbname + " - " + err.Error())
+		return nil, errors.New("databases: could not read stats db - " + err.Error())
 	}
-	return nil
+	return stats, nil
 }
 
-// ReadProfileFromDb read a character profile from a db provided db pointer
-func ReadProfileFromDb(db *badger.DB, ID int) (*characters.CharacterProfile, error) {
-	var data []byte
-	strID := strconv.Itoa(ID)
-	err := db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(strID))
-		if err != nil {
-			return err
-		}
-		data, err = item.ValueCopy(nil)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
+// ListSnapshotsDb list the stats snapshots of the stats db of a store spec provided a dbname, oldest first
+func ListSnapshotsDb(spec string, dbname string) ([]int64, error) {
+	store, err := OpenStore(spec, Scope{})
 	if err != nil {
-		return nil, errors.New("databases: could not read profile from db - " + err.Error())
+		return nil, errors.New("databases: could not list snapshots - " + err.Error())
 	}
-	characterProfile, err := helpers.DecodeProfile(data)
+	defer store.Close()
+	snapshots, err := store.Snapshots(dbname)
 	if err != nil {
-		return nil, errors.New("databases: could not read profile from db - " + err.Error())
+		return nil, errors.New("databases: could not list snapshots - " + err.Error())
 	}
-	return characterProfile, nil
+	return snapshots, nil
 }
 
-// ReadStatsDb read stats struct from the stats db provided a dbname
-func ReadStatsDb(dbname string) (*models.Stats, error) {
-	db, err := OpenDB("databases/stats")
+// DiffDb read the changes between two stats snapshots of the stats db of a store spec provided a dbname, see Diff
+func DiffDb(spec string, dbname string, from int64, to int64, movers int) (*models.Diff, error) {
+	store, err := OpenStore(spec, Scope{})
 	if err != nil {
-		return nil, errors.New("databases: could not read stats db - " + err.Error())
+		return nil, errors.New("databases: could not diff stats db - " + err.Error())
 	}
-	defer db.Close()
-	var data []byte
-	err = db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(dbname))
-		if err != nil {
-			return err
+	defer store.Close()
+	diff, err := Diff(store, dbname, from, to, movers)
+	if err != nil {
+		return nil, errors.New("databases: could not diff stats db - " + err.Error())
+	}
+	return diff, nil
+}
+
+// GenerateStatistics generate stats for a store, restricted to the profiles accepted by filter
+// Stores holding several regions have their regions combined unless the filter restrict them to one
+// Profiles are also broken down by faction, race, role and gender, and their item levels summarized per spec
+// Classes and specs get their share of the players with a confidence interval
+// Profiles collected from a pvp leatherboard also feed win rates and rating buckets, by class and spec, from their ladder entry
+// Profiles collected from a raid guild also feed the class repartition of their guild
+// Stores indexing several sources also count how many of the characters other sources hold
+// A character listed on several pvp brackets is counted once when the filter has no bracket or a bracket family, each of its ladder entries still feeding win rates and rating buckets
+// Ladder entries are attributed to the spec a shuffle-<class>-<spec> bracket name, otherwise to the spec of the profile listed on their bracket
+func GenerateStatistics(store Store, filter Filter) (*models.Stats, error) {
+	stats := &models.Stats{}
+	rated := []ratedEntry{}
+	cutoffs := map[string]int{}
+	overlaps := newOverlapCounter()
+	itemLevels := newItemLevelCounter()
+	counted := map[string]*models.Spec{}
+	err := regionsEntries(store, filter, func(view Store, region string, entry Entry) error {
+		if !filter.Accept(entry) {
+			return nil
 		}
-		data, err = item.ValueCopy(nil)
-		if err != nil {
-			return err
+		characterProfile := entry.Profile
+		key := region + "/" + strconv.Itoa(characterProfile.ID)
+		spec, ok := counted[key]
+		if !ok {
+			spec = stats.Count(characterProfile.CharacterClass.Name, characterProfile.ActiveSpec.Name)
+			counted[key] = spec
+			err := countCharacter(stats, view, region, entry, overlaps, itemLevels, spec)
+			if err != nil {
+				return err
+			}
+		}
+		if entry.Ladder != nil {
+			cutoffKey := region + "/" + entry.Ladder.Bracket
+			cutoff, ok := cutoffs[cutoffKey]
+			if !ok {
+				var err error
+				cutoff, err = view.Cutoff(entry.Ladder.Bracket)
+				if err != nil {
+					return err
+				}
+				cutoffs[cutoffKey] = cutoff
+			}
+			rated = append(rated, ratedEntry{
+				character: key,
+				class:     characterProfile.CharacterClass.Name,
+				spec:      characterProfile.ActiveSpec.Name,
+				ladder:    entry.Ladder,
+				cutoff:    cutoff,
+			})
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, errors.New("databases: could not read stats db - " + err.Error())
+		return nil, errors.New("databases: could not generate stats from db - " + err.Error())
 	}
-	stats, err := helpers.DecodeStats(data)
-	if err != nil {
-		return nil, errors.New("databases: could not read stats db - " + err.Error())
+	stats.Characters, stats.Overlaps = overlaps.result()
+	itemLevels.apply()
+	for i, entry := range rated {
+		rated[i].spec = ladderSpec(stats, entry.class, entry.spec, entry.ladder.Bracket)
+		spec := stats.AddSpec(entry.class, rated[i].spec)
+		spec.Played += entry.ladder.Played
+		spec.Won += entry.ladder.Won
+		spec.Lost += entry.ladder.Lost
 	}
+	for _, distrib := range stats.Distributions {
+		for _, spec := range distrib.Specs {
+			if spec.Played > 0 {
+				spec.WinRate = float64(spec.Won) / float64(spec.Played)
+			}
+		}
+	}
+	sort.SliceStable(stats.Guilds, func(i, j int) bool {
+		return stats.Guilds[i].RegionRank < stats.Guilds[j].RegionRank
+	})
+	stats.Ratings = bucketRatings(rated)
+	stats.ComputeShares()
 	return stats, nil
 }
 
-func GetStatsFromDb(db *badger.DB, dbname string) (*models.Stats, error) {
-	var data []byte
-	err := db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(dbname))
+// countCharacter add the breakdowns, item levels, overlaps and guild of the character of an entry, already counted in spec, to stats
+func countCharacter(stats *models.Stats, view Store, region string, entry Entry, overlaps *overlapCounter, itemLevels *itemLevelCounter, spec *models.Spec) error {
+	characterProfile := entry.Profile
+	itemLevels.add(spec, characterProfile)
+	stats.CountBreakdowns(characterProfile.CharacterClass.Name, characterProfile.Faction.Type, characterProfile.Race.Name, characterProfile.ActiveSpec.Role.Type, characterProfile.Gender.Type)
+	if indexer, ok := view.(Indexer); ok {
+		err := overlaps.add(indexer, region, characterProfile.ID)
 		if err != nil {
 			return err
 		}
-		data, err = item.ValueCopy(nil)
-		if err != nil {
-			return err
+	}
+	if entry.Guild != nil {
+		guildStats := stats.FindGuild(entry.Guild.Name, entry.Guild.Realm)
+		if guildStats == nil {
+			guildStats = &models.GuildStats{
+				Guild:      entry.Guild.Name,
+				Realm:      entry.Guild.Realm,
+				RegionRank: entry.Guild.RegionRank,
+				Timestamp:  entry.Guild.Timestamp,
+			}
+			stats.Guilds = append(stats.Guilds, guildStats)
 		}
-		return nil
+		guildStats.Count(characterProfile.CharacterClass.Name, characterProfile.ActiveSpec.Name)
+	}
+	return nil
+}
+
+// guildRef return the reference of the hall of fame entry of a guild for a raid, guilds being listed by every raid they cleared
+func guildRef(raid string, ID int) string {
+	return raid + "/" + strconv.Itoa(ID)
+}
+
+// memberGuild return the guild a member was collected from, or the most recent hall of fame entry of its guild for members recorded without their raid
+// Entries of the same time are told apart by raid name so every store pick the same one
+func memberGuild(guilds map[string]*models.Guild, member *models.Member) *models.Guild {
+	if member.Raid != "" {
+		return guilds[guildRef(member.Raid, member.GuildID)]
+	}
+	var found *models.Guild
+	for _, guild := range guilds {
+		if guild.ID != member.GuildID {
+			continue
+		}
+		if found == nil || guild.Timestamp > found.Timestamp || (guild.Timestamp == found.Timestamp && guild.Raid < found.Raid) {
+			found = guild
+		}
+	}
+	return found
+}
+
+// regionsEntries call fn for every entry of the store matching the query of filter in its region, or in every region of the store source if filter has none
+// fn is given the store view and region the entry was read from
+func regionsEntries(store Store, filter Filter, fn func(view Store, region string, entry Entry) error) error {
+	return regionViews(store, filter.Region, func(view Store, region string) error {
+		return QueryEntries(view, filter.Bracket, filter.query(), crawlEntries(view, filter, func(entry Entry) error {
+			return fn(view, region, entry)
+		}))
 	})
-	if err != nil {
-		return nil, errors.New("databases: could not read stats from db - " + err.Error())
+}
+
+// regionViews call fn with the store if region is provided or the store hold a single region, or else with a view of every region of the store source
+func regionViews(store Store, region string, fn func(view Store, region string) error) error {
+	regioner, ok := store.(Regioner)
+	if !ok || region != "" {
+		return fn(store, region)
 	}
-	stats, err := helpers.DecodeStats(data)
+	regions, err := regioner.Regions()
 	if err != nil {
-		return nil, errors.New("databases: could not read stats from db - " + err.Error())
+		return err
 	}
-	return stats, nil
+	for _, region := range regions {
+		err = fn(regioner.WithRegion(region), region)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// GenerateStatistics generate stats for a db provided a db pointer
-func GenerateStatistics(db *badger.DB) (*models.Stats, error) {
-	stats := &models.Stats{}
-	err := db.View(func(tnx *badger.Txn) error {
-		options := badger.DefaultIteratorOptions
-		iterator := tnx.NewIterator(options)
-		defer iterator.Close()
-		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
-			item := iterator.Item()
-			data, err := item.ValueCopy(nil)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			characterProfile, err := helpers.DecodeProfile(data)
+// crawlEntries wrap fn to skip entries not listed by the latest complete crawl of their bracket, or raid for raid members, when filter keep the latest crawl only
+// Brackets and raids no crawl completed for keep every entry
+func crawlEntries(store Store, filter Filter, fn func(entry Entry) error) func(entry Entry) error {
+	if !filter.LatestCrawl {
+		return fn
+	}
+	crawls := map[string]int64{}
+	return func(entry Entry) error {
+		bracket := ""
+		if entry.Ladder != nil {
+			bracket = entry.Ladder.Bracket
+		}
+		if entry.Provenance != nil {
+			bracket = entry.Provenance.Bracket
+		}
+		if bracket == "" && entry.Member != nil {
+			bracket = entry.Member.Raid
+		}
+		crawl, ok := crawls[bracket]
+		if !ok {
+			var err error
+			crawl, err = store.LatestCrawl(bracket)
 			if err != nil {
-				log.Println(err)
-				continue
-			}
-			distrib := stats.FindDistribution(characterProfile.CharacterClass.Name)
-			if distrib == nil {
-				distrib = &models.Distribution{
-					Class: characterProfile.CharacterClass.Name,
-					Total: 1,
-				}
-				stats.Distributions = append(stats.Distributions, distrib)
-			} else {
-				distrib.Total++
-			}
-			spec := distrib.FindSpec(characterProfile.ActiveSpec.Name)
-			if spec == nil {
-				spec = &models.Spec{
-					Spec:  characterProfile.ActiveSpec.Name,
-					Count: 1,
-				}
-				distrib.Specs = append(distrib.Specs, spec)
-			} else {
-				spec.Count++
+				return err
 			}
-			stats.Overall++
+			crawls[bracket] = crawl
+		}
+		if crawl > 0 && (entry.Provenance == nil || entry.Provenance.Crawl < crawl) {
+			return nil
+		}
+		return fn(entry)
+	}
+}
+
+// ladderSpec return the spec a ladder entry of a bracket is attributed to, the counted spec of class named by a shuffle-<class>-<spec> bracket, otherwise provided profile spec
+func ladderSpec(stats *models.Stats, class string, spec string, bracket string) string {
+	parts := strings.SplitN(bracket, "-", 3)
+	distrib := stats.FindDistribution(class)
+	if len(parts) != 3 || parts[0] != "shuffle" || distrib == nil {
+		return spec
+	}
+	for _, found := range distrib.Specs {
+		if strings.ReplaceAll(indexValue(found.Spec), "-", "") == parts[2] {
+			return found.Spec
 		}
+	}
+	return spec
+}
+
+// ratedEntry hold a ladder entry with the character, class and spec it was listed for and the rank 1 cutoff of its bracket, 0 if unknown
+type ratedEntry struct {
+	character string
+	class     string
+	spec      string
+	ladder    *models.Ladder
+	cutoff    int
+}
+
+// bucketRatings count the characters at or above each rating bucket and above the rank 1 cutoff of their bracket, by class and spec
+// A character listed on several brackets is counted once per bucket, the rank 1 bucket being left out when no cutoff was published with the season rewards
+func bucketRatings(rated []ratedEntry) []*models.RatingBucket {
+	if len(rated) == 0 {
 		return nil
+	}
+	buckets := []*models.RatingBucket{}
+	for _, rating := range ratingBuckets {
+		bucket := &models.RatingBucket{
+			Label:  strconv.Itoa(rating) + "+",
+			Rating: rating,
+		}
+		countRated(bucket, rated, func(entry ratedEntry) bool {
+			return entry.ladder.Rating >= rating
+		})
+		buckets = append(buckets, bucket)
+	}
+	cutoffs := map[int]bool{}
+	for _, entry := range rated {
+		if entry.cutoff > 0 {
+			cutoffs[entry.cutoff] = true
+		}
+	}
+	if len(cutoffs) == 0 {
+		return buckets
+	}
+	rankOne := &models.RatingBucket{
+		Label: "rank 1",
+	}
+	if len(cutoffs) == 1 {
+		for cutoff := range cutoffs {
+			rankOne.Rating = cutoff
+		}
+	}
+	countRated(rankOne, rated, func(entry ratedEntry) bool {
+		return entry.cutoff > 0 && entry.ladder.Rating >= entry.cutoff
 	})
+	return append(buckets, rankOne)
+}
+
+// countRated count in bucket every character with a rated entry accepted by keep
+func countRated(bucket *models.RatingBucket, rated []ratedEntry, keep func(entry ratedEntry) bool) {
+	counted := map[string]bool{}
+	for _, entry := range rated {
+		if counted[entry.character] || !keep(entry) {
+			continue
+		}
+		counted[entry.character] = true
+		bucket.Count(entry.class, entry.spec)
+	}
+}
+
+// WriteStatsForDb compute and write stats for provided source of a store spec, restricted to the profiles accepted by filter, and return them
+// Stats of every region of the source are combined unless the filter restrict them to a region
+// The endgame source combine every endgame source, counting each character once
+func WriteStatsForDb(spec string, source string, filter Filter) (*models.Stats, error) {
+	scope := Scope{Source: source, Region: filter.Region}
+	if source == EndgameSource {
+		scope.Source = ""
+	}
+	store, err := OpenStore(spec, scope)
 	if err != nil {
-		return nil, errors.New("databases: could not generate stats from db - " + err.Error())
+		return nil, errors.New("databases: could not save stats for db " + source + " - " + err.Error())
+	}
+	defer store.Close()
+	var stats *models.Stats
+	if source == EndgameSource {
+		stats, err = GenerateEndgameStatistics(store, filter)
+	} else {
+		stats, err = GenerateStatistics(store, filter)
+	}
+	if err != nil {
+		return nil, errors.New("databases: could not save stats for db " + source + " - " + err.Error())
+	}
+	err = writeStats(store, stats, source, filter)
+	if err != nil {
+		return nil, errors.New("databases: could not save stats for db " + source + " - " + err.Error())
 	}
 	return stats, nil
 }
 
-// WriteStatsForDb compute and write stats for provided path db
-func WriteStatsForDb(dbname string) error {
-	db, err := OpenDB("databases/" + dbname)
-	if err != nil {
-		return errors.New("databases: could not save stats for db " + dbname + " - " + err.Error())
+// importLegacyDb import the legacy badger db of a source, if any, into a store and rename it with a .legacy suffix
+// Opening a store imports it so profiles stored under bare character ID keys are never left out of stats
+// Legacy dbs are imported to the store region, or to LegacyRegion for a store opened for every region, which is recorded so stats list it
+func importLegacyDb(store *BadgerStore, path string, source string) (int, error) {
+	if source == "" {
+		return 0, nil
 	}
-	defer db.Close()
-	stats, err := GenerateStatistics(db)
+	legacy := filepath.Join(path, source)
+	if _, err := os.Stat(filepath.Join(legacy, "MANIFEST")); err != nil {
+		return 0, nil
+	}
+	scope := Scope{Source: source, Region: store.scope.Region}
+	if scope.Region == "" {
+		scope.Region = LegacyRegion
+	}
+	target := &BadgerStore{db: store.db, scope: scope, view: true}
+	imported, err := target.ImportLegacy(legacy)
 	if err != nil {
-		return errors.New("databases: could not save stats for db " + dbname + " - " + err.Error())
+		return 0, err
+	}
+	if source != statsSource {
+		err = target.set(scope.MetaKey(), nil)
+		if err != nil {
+			return 0, err
+		}
 	}
-	err = WriteStatsToDb(*stats, dbname)
+	err = os.Rename(legacy, legacy+".legacy")
 	if err != nil {
-		return errors.New("databases: could not save stats for db " + dbname + " - " + err.Error())
+		return 0, err
 	}
-	return nil
+	return imported, nil
 }
 
-// OpenDB open a db at provided path and return a db pointer
-func OpenDB(path string) (*badger.DB, error) {
-	options := badger.DefaultOptions(path)
-	options.Logger = nil
-	options.Truncate = true
-	db, err := badger.Open(options)
+// MigrateDb open the store of a spec for provided scope, which import its legacy badger db if any, then rewrite its profiles to the current record version
+// A legacy badger db is a per source directory in the badger directory, renamed with a .legacy suffix once imported
+// It return how many keys were rewritten, stores that do not keep encoded profiles having nothing to migrate
+func MigrateDb(spec string, scope Scope) (int, error) {
+	store, err := OpenStore(spec, scope)
+	if err != nil {
+		return 0, errors.New("databases: could not migrate db " + scope.Source + " - " + err.Error())
+	}
+	defer store.Close()
+	migrator, ok := store.(Migrator)
+	if !ok {
+		return 0, nil
+	}
+	rewritten, err := migrator.Migrate()
 	if err != nil {
-		return nil, errors.New("databases: could not open db - " + err.Error())
+		return 0, errors.New("databases: could not migrate db " + scope.Source + " - " + err.Error())
 	}
-	return db, nil
+	return rewritten, nil
 }
@@ -0,0 +1,50 @@
+package databases
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"wowstatistician/characters"
+	"wowstatistician/models"
+)
+
+// testProfile return a valid profile of provided ID, class and spec
+func testProfile(ID int, class string, spec string) characters.CharacterProfile {
+	var characterProfile characters.CharacterProfile
+	characterProfile.ID = ID
+	characterProfile.Name = "character"
+	characterProfile.Realm.ID = 1
+	characterProfile.Realm.Slug = "kazzak"
+	characterProfile.CharacterClass.ID = 1
+	characterProfile.CharacterClass.Name = class
+	characterProfile.ActiveSpec.ID = 1
+	characterProfile.ActiveSpec.Name = spec
+	return characterProfile
+}
+
+// testRow return a valid profile row of provided ID
+func testRow(ID int64) models.ProfileRow {
+	return models.ProfileRow{
+		ID:      ID,
+		Name:    "character",
+		Realm:   "kazzak",
+		RealmID: 1,
+		Class:   "Mage",
+		ClassID: 8,
+		Spec:    "Fire",
+		SpecID:  63,
+	}
+}
+
+// testSpec return the spec of a store of provided kind in a temporary directory, removed when the test ends
+func testSpec(t *testing.T, kind string) string {
+	dir, err := ioutil.TempDir("", "wowstatistician")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return kind + ":" + filepath.Join(dir, "db")
+}
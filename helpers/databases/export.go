@@ -0,0 +1,233 @@
+package databases
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"wowstatistician/helpers"
+	"wowstatistician/models"
+)
+
+// ExportKinds list what can be exported from a store
+var ExportKinds = []string{"profiles", "stats", "runs"}
+
+// ExportDb stream the profiles, runs or stats of provided db of a store spec to w as rows in provided format, and return how many rows were written
+// Profiles and runs of every region of the db are exported unless restricted to a region, stats are every snapshot of every stats generated for the db
+func ExportDb(spec string, dbname string, region string, what string, w io.Writer, format string) (int, error) {
+	scope := Scope{Source: dbname, Region: region}
+	if what == "stats" {
+		scope = Scope{}
+	}
+	store, err := OpenStore(spec, scope)
+	if err != nil {
+		return 0, errors.New("databases: could not export db " + dbname + " - " + err.Error())
+	}
+	defer store.Close()
+	var rows int
+	switch what {
+	case "profiles":
+		rows, err = exportProfiles(store, dbname, Filter{Region: region}, w, format)
+	case "runs":
+		rows, err = exportRuns(store, region, w, format)
+	case "stats":
+		rows, err = exportStats(store, dbname, region, w, format)
+	default:
+		err = errors.New("unknown export: " + what + ", expected one of: " + strings.Join(ExportKinds, ", "))
+	}
+	if err != nil {
+		return 0, errors.New("databases: could not export db " + dbname + " - " + err.Error())
+	}
+	return rows, nil
+}
+
+// QueryDb stream the profiles of provided db of a store spec matching the region, bracket, query and conditions of filter to w as rows in provided format, and return how many rows were written
+func QueryDb(spec string, dbname string, filter Filter, w io.Writer, format string) (int, error) {
+	store, err := OpenStore(spec, Scope{Source: dbname, Region: filter.Region})
+	if err != nil {
+		return 0, errors.New("databases: could not query db " + dbname + " - " + err.Error())
+	}
+	defer store.Close()
+	rows, err := exportProfiles(store, dbname, filter, w, format)
+	if err != nil {
+		return 0, errors.New("databases: could not query db " + dbname + " - " + err.Error())
+	}
+	return rows, nil
+}
+
+// exportProfiles stream every profile of a store matching the region, bracket, query and conditions of filter with its collection context as models.ProfileRow
+func exportProfiles(store Store, source string, filter Filter, w io.Writer, format string) (int, error) {
+	rowWriter, err := helpers.NewRowWriter(w, format, new(models.ProfileRow))
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	profiles := Filter{Region: filter.Region, Bracket: filter.Bracket, Query: filter.Query, Conditions: filter.Conditions}
+	err = regionsEntries(store, profiles, func(view Store, region string, entry Entry) error {
+		if !profiles.Accept(entry) {
+			return nil
+		}
+		rows++
+		return rowWriter.Write(profileRow(source, region, entry))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rows, rowWriter.Close()
+}
+
+// profileRow flatten an entry of provided source and region
+func profileRow(source string, region string, entry Entry) *models.ProfileRow {
+	characterProfile := entry.Profile
+	row := &models.ProfileRow{
+		Source:            source,
+		Region:            region,
+		ID:                int64(characterProfile.ID),
+		Name:              characterProfile.Name,
+		Realm:             characterProfile.Realm.Slug,
+		RealmID:           int64(characterProfile.Realm.ID),
+		Faction:           characterProfile.Faction.Type,
+		Race:              characterProfile.Race.Name,
+		Gender:            characterProfile.Gender.Type,
+		Class:             characterProfile.CharacterClass.Name,
+		ClassID:           int64(characterProfile.CharacterClass.ID),
+		Spec:              characterProfile.ActiveSpec.Name,
+		SpecID:            int64(characterProfile.ActiveSpec.ID),
+		Role:              characterProfile.ActiveSpec.Role.Type,
+		Level:             int64(characterProfile.Level),
+		AverageItemLevel:  int64(characterProfile.AverageItemLevel),
+		EquippedItemLevel: int64(characterProfile.EquippedItemLevel),
+		LastLogin:         int64(characterProfile.LastLoginTimestamp),
+	}
+	if entry.Ladder != nil {
+		row.Bracket = entry.Ladder.Bracket
+		row.Season = int64(entry.Ladder.Season)
+		row.Rating = int64(entry.Ladder.Rating)
+		row.Rank = int64(entry.Ladder.Rank)
+		row.Played = int64(entry.Ladder.Played)
+		row.Won = int64(entry.Ladder.Won)
+		row.Lost = int64(entry.Ladder.Lost)
+	}
+	if entry.Guild != nil {
+		row.GuildID = int64(entry.Guild.ID)
+		row.Guild = entry.Guild.Name
+		row.GuildRealm = entry.Guild.Realm
+		row.GuildRegionRank = int64(entry.Guild.RegionRank)
+		row.GuildRaid = entry.Guild.Raid
+	}
+	if entry.Member != nil {
+		row.RosterRank = int64(entry.Member.RosterRank)
+	}
+	if entry.Provenance != nil {
+		row.Bracket = entry.Provenance.Bracket
+		row.Leatherboard = entry.Provenance.Leatherboard
+		row.Crawl = entry.Provenance.Crawl
+		row.FetchedAt = entry.Provenance.FetchedAt
+	}
+	return row
+}
+
+// exportRuns stream every member of every mythic+ run of a store as models.RunRow
+func exportRuns(store Store, region string, w io.Writer, format string) (int, error) {
+	rowWriter, err := helpers.NewRowWriter(w, format, new(models.RunRow))
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	err = regionViews(store, region, func(view Store, region string) error {
+		return view.Runs(func(run models.Run) error {
+			for _, member := range run.Members {
+				rows++
+				err := rowWriter.Write(&models.RunRow{
+					Region:             region,
+					RunID:              run.ID,
+					Dungeon:            run.Dungeon,
+					Period:             int64(run.Period),
+					Ranking:            int64(run.Ranking),
+					KeystoneLevel:      int64(run.KeystoneLevel),
+					Duration:           int64(run.Duration),
+					CompletedTimestamp: int64(run.CompletedTimestamp),
+					MemberID:           int64(member.ID),
+					Name:               member.Name,
+					Realm:              member.Realm,
+					Class:              member.Class,
+					Spec:               member.Spec,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rows, rowWriter.Close()
+}
+
+// exportStats stream every spec of every snapshot of the stats generated for a db as models.StatsRow, restricted to the stats of a region when provided
+func exportStats(store Store, dbname string, region string, w io.Writer, format string) (int, error) {
+	rowWriter, err := helpers.NewRowWriter(w, format, new(models.StatsRow))
+	if err != nil {
+		return 0, err
+	}
+	names, err := store.StatsNames()
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	for _, name := range names {
+		if name != dbname && !strings.HasPrefix(name, dbname+":") {
+			continue
+		}
+		// Every snapshot of a stats name share its region, so the latest one tell whether the name is exported
+		if region != "" {
+			latest, err := store.ReadStats(name)
+			if err != nil {
+				return 0, err
+			}
+			if latest.Region != region {
+				continue
+			}
+		}
+		snapshots, err := store.Snapshots(name)
+		if err != nil {
+			return 0, err
+		}
+		for _, snapshot := range snapshots {
+			stats, err := store.ReadSnapshot(name, snapshot)
+			if err != nil {
+				return 0, err
+			}
+			for _, distrib := range stats.Distributions {
+				for _, spec := range distrib.Specs {
+					row := &models.StatsRow{
+						Name:     name,
+						Snapshot: snapshot,
+						SyncDate: stats.SyncDate,
+						Source:   stats.Source,
+						Region:   stats.Region,
+						Bracket:  stats.Bracket,
+						Class:    distrib.Class,
+						Spec:     spec.Spec,
+						Count:    int64(spec.Count),
+						Overall:  int64(stats.Overall),
+						Played:   int64(spec.Played),
+						Won:      int64(spec.Won),
+						Lost:     int64(spec.Lost),
+						WinRate:  spec.WinRate,
+					}
+					if stats.Overall > 0 {
+						row.Share = float64(spec.Count) / float64(stats.Overall)
+					}
+					rows++
+					err = rowWriter.Write(row)
+					if err != nil {
+						return 0, err
+					}
+				}
+			}
+		}
+	}
+	return rows, rowWriter.Close()
+}
@@ -0,0 +1,42 @@
+package databases
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"wowstatistician/models"
+)
+
+func TestExportStatsRegion(t *testing.T) {
+	store := NewMemoryStore()
+	for name, region := range map[string]string{"arena": "", "arena:eu": "eu", "arena:us": "us", "raid:us": "us"} {
+		for _, snapshot := range []int64{100, 200} {
+			stats := models.Stats{Snapshot: snapshot, Region: region}
+			stats.Count("Mage", "Fire")
+			err := store.WriteStats(name, stats)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	rows, err := exportStats(store, "arena", "us", &buffer, "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the snapshots of the stats names of the region are exported
+	if rows != 2 {
+		t.Errorf("rows = %v, want 2", rows)
+	}
+	decoder := json.NewDecoder(&buffer)
+	for decoder.More() {
+		var row models.StatsRow
+		err := decoder.Decode(&row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.Name != "arena:us" {
+			t.Errorf("exported row of %v, want arena:us", row.Name)
+		}
+	}
+}
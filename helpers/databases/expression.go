@@ -0,0 +1,184 @@
+package databases
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Condition is a term of a filter expression comparing a profile field to a value, ie: ilvl>=470
+type Condition struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// stringFields list the text fields conditions compare, with = and != only and case insensitively
+var stringFields = []string{"class", "spec", "realm", "faction", "race", "gender", "role"}
+
+// numberFields list the numeric fields conditions compare, rating being the pvp ladder rating of a profile
+var numberFields = []string{"ilvl", "avgilvl", "level", "rating"}
+
+var conditionPattern = regexp.MustCompile(`^([a-z]+)(>=|<=|!=|=|>|<)(.+)$`)
+
+// ParseFilterExpression parse a space separated list of conditions, ie: faction=HORDE realm=kazzak ilvl>=470
+// Text fields are class, spec, realm, faction, race, gender and role, numeric fields are ilvl, avgilvl, level and rating
+// Multi-word values are written with dashes or quoted, ie: spec=beast-mastery or spec="Beast Mastery"
+func ParseFilterExpression(expression string) ([]Condition, error) {
+	conditions := []Condition{}
+	terms, err := splitTerms(expression)
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range terms {
+		match := conditionPattern.FindStringSubmatch(strings.ToLower(term))
+		if match == nil {
+			return nil, errors.New("databases: could not parse filter - invalid condition: " + term)
+		}
+		condition := Condition{Field: match[1], Operator: match[2], Value: match[3]}
+		switch {
+		case contains(stringFields, condition.Field):
+			if condition.Operator != "=" && condition.Operator != "!=" {
+				return nil, errors.New("databases: could not parse filter - " + condition.Field + " can only be compared with = or !=")
+			}
+			condition.Value = indexValue(condition.Value)
+		case contains(numberFields, condition.Field):
+			_, err := strconv.Atoi(condition.Value)
+			if err != nil {
+				return nil, errors.New("databases: could not parse filter - " + condition.Field + " must be compared to a number, got: " + condition.Value)
+			}
+		default:
+			return nil, errors.New("databases: could not parse filter - unknown field: " + condition.Field + ", expected one of: " + strings.Join(append(append([]string{}, stringFields...), numberFields...), ", "))
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// splitTerms split an expression on spaces, except for spaces within double quotes which are removed
+func splitTerms(expression string) ([]string, error) {
+	terms := []string{}
+	term := strings.Builder{}
+	quoted := false
+	for _, r := range expression {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.New("databases: could not parse filter - unterminated quote in: " + expression)
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
+
+// String return the canonical form of the condition, ie: ilvl>=470
+func (c Condition) String() string {
+	return c.Field + c.Operator + c.Value
+}
+
+// Accept return true if a profile collected in provided context meet the condition
+func (c Condition) Accept(entry Entry) bool {
+	characterProfile := entry.Profile
+	switch c.Field {
+	case "class":
+		return c.compareText(characterProfile.CharacterClass.Name)
+	case "spec":
+		return c.compareText(characterProfile.ActiveSpec.Name)
+	case "realm":
+		return c.compareText(characterProfile.Realm.Slug)
+	case "faction":
+		return c.compareText(characterProfile.Faction.Type)
+	case "race":
+		return c.compareText(characterProfile.Race.Name)
+	case "gender":
+		return c.compareText(characterProfile.Gender.Type)
+	case "role":
+		return c.compareText(characterProfile.ActiveSpec.Role.Type)
+	case "ilvl":
+		return c.compareNumber(characterProfile.EquippedItemLevel)
+	case "avgilvl":
+		return c.compareNumber(characterProfile.AverageItemLevel)
+	case "level":
+		return c.compareNumber(characterProfile.Level)
+	case "rating":
+		return entry.Ladder != nil && c.compareNumber(entry.Ladder.Rating)
+	}
+	return false
+}
+
+func (c Condition) compareText(value string) bool {
+	equal := indexValue(value) == indexValue(c.Value)
+	if c.Operator == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+func (c Condition) compareNumber(value int) bool {
+	expected, _ := strconv.Atoi(c.Value)
+	switch c.Operator {
+	case "=":
+		return value == expected
+	case "!=":
+		return value != expected
+	case ">=":
+		return value >= expected
+	case "<=":
+		return value <= expected
+	case ">":
+		return value > expected
+	case "<":
+		return value < expected
+	}
+	return false
+}
+
+// queryConditions return the conditions equivalent to a query, one per field it sets
+func queryConditions(query Query) []Condition {
+	conditions := []Condition{}
+	values := query.values()
+	for _, field := range []string{"class", "spec", "realm", "faction"} {
+		if values[field] != "" {
+			conditions = append(conditions, Condition{Field: field, Operator: "=", Value: values[field]})
+		}
+	}
+	return conditions
+}
+
+// canonicalConditions return the sorted and deduplicated canonical forms of conditions
+func canonicalConditions(conditions []Condition) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, condition := range conditions {
+		term := condition.String()
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
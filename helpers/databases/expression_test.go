@@ -0,0 +1,72 @@
+package databases
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilterExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       []Condition
+		wantErr    bool
+	}{
+		{
+			name:       "single words",
+			expression: "faction=HORDE ilvl>=470",
+			want:       []Condition{{Field: "faction", Operator: "=", Value: "horde"}, {Field: "ilvl", Operator: ">=", Value: "470"}},
+		},
+		{
+			name:       "dashed multi-word value",
+			expression: "spec=beast-mastery",
+			want:       []Condition{{Field: "spec", Operator: "=", Value: "beast-mastery"}},
+		},
+		{
+			name:       "quoted multi-word values",
+			expression: `spec="Beast Mastery" "race!=Night Elf"`,
+			want:       []Condition{{Field: "spec", Operator: "=", Value: "beast-mastery"}, {Field: "race", Operator: "!=", Value: "night-elf"}},
+		},
+		{name: "unterminated quote", expression: `class="death knight`, wantErr: true},
+		{name: "ordered text field", expression: "class>mage", wantErr: true},
+		{name: "unknown field", expression: "guild=test", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conditions, err := ParseFilterExpression(test.expression)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseFilterExpression(%q) = %v, want an error", test.expression, conditions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(conditions, test.want) {
+				t.Errorf("ParseFilterExpression(%q) = %v, want %v", test.expression, conditions, test.want)
+			}
+		})
+	}
+}
+
+func TestMultiWordFilterMatch(t *testing.T) {
+	store := NewMemoryStore()
+	for ID, spec := range map[int]string{1: "Beast Mastery", 2: "Marksmanship"} {
+		err := store.WriteProfile("", testProfile(ID, "Hunter", spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	conditions, err := ParseFilterExpression(`spec="Beast Mastery"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := GenerateStatistics(store, Filter{Conditions: conditions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Overall != 1 {
+		t.Errorf("overall = %v, want 1", stats.Overall)
+	}
+}
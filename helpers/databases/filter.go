@@ -0,0 +1,143 @@
+package databases
+
+import (
+	"strconv"
+	"strings"
+	"wowstatistician/characters"
+	"wowstatistician/models"
+)
+
+// Entry bundle a character profile with the context it was collected from
+// Ladder is nil unless the profile comes from a pvp leatherboard, Member and Guild are nil unless it comes from a raid guild
+// Provenance is nil for profiles collected before provenance was recorded
+type Entry struct {
+	Profile    *characters.CharacterProfile
+	Ladder     *models.Ladder
+	Member     *models.Member
+	Guild      *models.Guild
+	Provenance *models.Provenance
+}
+
+// Filter restrict the profiles stats are generated from
+type Filter struct {
+	// Region is the region profiles are kept from - empty combine every region of the source
+	Region    string
+	Bracket   string
+	MinRating int
+	Top       int
+	TopGuilds int
+	// RosterRanks is the number of guild roster ranks raid members are kept from, ie: 4 keep ranks 0 to 3 - 0 keep every rank
+	RosterRanks int
+	// LatestCrawl keep only profiles listed by the latest complete crawl of their bracket, dropping characters that fell off the leatherboards
+	LatestCrawl bool
+	// Query restrict stats to profiles of a class, spec, realm or faction, looked up by the store indexes
+	Query Query
+	// Conditions restrict stats to profiles meeting every condition, as parsed by ParseFilterExpression
+	Conditions []Condition
+}
+
+// Name return the name stats generated with the filter are stored under for a dbname - ie: arena, arena:eu, arena:eu:3v3, arena:3v3:2400+, arena:top500, raid:us:top100guilds:ranks0-3, arena:3v3:latest or raid:class=paladin:ilvl>=470:spec=holy
+func (f Filter) Name(dbname string) string {
+	name := dbname
+	if f.Region != "" {
+		name += ":" + f.Region
+	}
+	if f.Bracket != "" {
+		name += ":" + f.Bracket
+	}
+	if f.MinRating > 0 {
+		name += ":" + strconv.Itoa(f.MinRating) + "+"
+	}
+	if f.Top > 0 {
+		name += ":top" + strconv.Itoa(f.Top)
+	}
+	if f.TopGuilds > 0 {
+		name += ":top" + strconv.Itoa(f.TopGuilds) + "guilds"
+	}
+	if f.RosterRanks > 0 {
+		name += ":ranks0-" + strconv.Itoa(f.RosterRanks-1)
+	}
+	if f.LatestCrawl {
+		name += ":latest"
+	}
+	for _, term := range f.Terms() {
+		name += ":" + term
+	}
+	return name
+}
+
+// Terms return the canonical, sorted, conditions of the filter query and expression, ie: [faction=horde ilvl>=470]
+func (f Filter) Terms() []string {
+	return canonicalConditions(append(queryConditions(f.Query), f.Conditions...))
+}
+
+// Expression return the filter expression of the filter query and conditions, ie: faction=horde ilvl>=470
+func (f Filter) Expression() string {
+	return strings.Join(f.Terms(), " ")
+}
+
+// query return the filter query completed with the equality conditions on indexed fields, so matches are looked up by the store indexes
+func (f Filter) query() Query {
+	query := f.Query
+	for _, condition := range f.Conditions {
+		if condition.Operator != "=" {
+			continue
+		}
+		switch {
+		case condition.Field == "class" && query.Class == "":
+			query.Class = condition.Value
+		case condition.Field == "spec" && query.Spec == "":
+			query.Spec = condition.Value
+		case condition.Field == "realm" && query.Realm == "":
+			query.Realm = condition.Value
+		case condition.Field == "faction" && query.Faction == "":
+			query.Faction = condition.Value
+		}
+	}
+	return query
+}
+
+// Accept return true if a profile collected in provided context pass the filter
+func (f Filter) Accept(entry Entry) bool {
+	if f.MinRating > 0 || f.Top > 0 {
+		if entry.Ladder == nil {
+			return false
+		}
+		if f.MinRating > 0 && entry.Ladder.Rating < f.MinRating {
+			return false
+		}
+		if f.Top > 0 && (entry.Ladder.Rank <= 0 || entry.Ladder.Rank > f.Top) {
+			return false
+		}
+	}
+	if f.TopGuilds > 0 {
+		if entry.Guild == nil {
+			return false
+		}
+		if entry.Guild.RegionRank <= 0 || entry.Guild.RegionRank > f.TopGuilds {
+			return false
+		}
+	}
+	if f.RosterRanks > 0 {
+		if entry.Member == nil || entry.Member.RosterRank >= f.RosterRanks {
+			return false
+		}
+	}
+	for _, condition := range f.Conditions {
+		if !condition.Accept(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// RegionName return the name of the stats of provided region matching the combined stats of provided name - ie: arena:3v3 become arena:eu:3v3
+func RegionName(name string, region string) string {
+	if region == "" {
+		return name
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i] + ":" + region + name[i:]
+	}
+	return name + ":" + region
+}
@@ -0,0 +1,162 @@
+package databases
+
+import (
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"wowstatistician/characters"
+	"wowstatistician/helpers"
+	"wowstatistician/models"
+)
+
+// ImportDb read profile rows, as written by ExportDb, from r in provided format and write them to provided db of a store spec
+// Rows are written to the region they carry, or to provided region if they carry none
+// Invalid rows, rows of another source and rows without region are logged and skipped, it return how many rows were imported and skipped
+func ImportDb(spec string, dbname string, region string, r io.Reader, format string) (int, int, error) {
+	rowReader, err := helpers.NewRowReader(r, format)
+	if err != nil {
+		return 0, 0, errors.New("databases: could not import db " + dbname + " - " + err.Error())
+	}
+	var store Store
+	storeRegion := ""
+	defer func() {
+		if store != nil {
+			store.Close()
+		}
+	}()
+	imported, skipped := 0, 0
+	for line := 1; ; line++ {
+		var row models.ProfileRow
+		err := rowReader.Read(&row)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, skipped, errors.New("databases: could not import db " + dbname + " - row " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		if row.Region == "" {
+			row.Region = region
+		}
+		err = checkImportRow(row, dbname)
+		if err != nil {
+			log.Println("databases: skipping row " + strconv.Itoa(line) + " - " + err.Error())
+			skipped++
+			continue
+		}
+		// Stores are reopened on region change, exported rows being grouped by region
+		if store == nil || storeRegion != row.Region {
+			if store != nil {
+				store.Close()
+			}
+			store, err = OpenStore(spec, Scope{Source: dbname, Region: row.Region})
+			if err != nil {
+				store = nil
+				return imported, skipped, errors.New("databases: could not import db " + dbname + " - " + err.Error())
+			}
+			storeRegion = row.Region
+		}
+		err = importRow(store, row)
+		if err != nil {
+			return imported, skipped, errors.New("databases: could not import db " + dbname + " - row " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		imported++
+	}
+	return imported, skipped, nil
+}
+
+// checkImportRow return why a row can not be imported to provided db, nil if it can
+func checkImportRow(row models.ProfileRow, dbname string) error {
+	if row.Source != "" && row.Source != dbname {
+		return errors.New("row of source " + row.Source + " can not be imported to db " + dbname)
+	}
+	if row.Region == "" {
+		return errors.New("row carry no region and no default region was provided")
+	}
+	return helpers.CheckValidProfileRow(row)
+}
+
+// importRow write the profile of a row along its ladder entry, guild membership and provenance when it carries them
+func importRow(store Store, row models.ProfileRow) error {
+	var characterProfile characters.CharacterProfile
+	characterProfile.ID = int(row.ID)
+	characterProfile.Name = row.Name
+	characterProfile.Realm.ID = int(row.RealmID)
+	characterProfile.Realm.Slug = row.Realm
+	characterProfile.Faction.Type = row.Faction
+	characterProfile.Race.Name = row.Race
+	characterProfile.Gender.Type = row.Gender
+	characterProfile.CharacterClass.ID = int(row.ClassID)
+	characterProfile.CharacterClass.Name = row.Class
+	characterProfile.ActiveSpec.ID = int(row.SpecID)
+	characterProfile.ActiveSpec.Name = row.Spec
+	characterProfile.ActiveSpec.PlayableClass = characterProfile.CharacterClass
+	characterProfile.ActiveSpec.Role.Type = row.Role
+	characterProfile.Level = int(row.Level)
+	characterProfile.AverageItemLevel = int(row.AverageItemLevel)
+	characterProfile.EquippedItemLevel = int(row.EquippedItemLevel)
+	characterProfile.LastLoginTimestamp = int(row.LastLogin)
+	err := store.WriteProfile(row.Bracket, characterProfile)
+	if err != nil {
+		return err
+	}
+	if row.Bracket != "" {
+		err = store.WriteLadder(models.Ladder{
+			ID:      characterProfile.ID,
+			Bracket: row.Bracket,
+			Season:  int(row.Season),
+			Rating:  int(row.Rating),
+			Rank:    int(row.Rank),
+			Played:  int(row.Played),
+			Won:     int(row.Won),
+			Lost:    int(row.Lost),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if row.GuildID > 0 {
+		err = store.WriteGuild(importGuild(store, row))
+		if err != nil {
+			return err
+		}
+		err = store.WriteMember(models.Member{ID: characterProfile.ID, GuildID: int(row.GuildID), Raid: row.GuildRaid, RosterRank: int(row.RosterRank)})
+		if err != nil {
+			return err
+		}
+	}
+	if row.Leatherboard != "" || row.Crawl > 0 || row.FetchedAt > 0 {
+		err = store.WriteProvenance(models.Provenance{
+			ID:           characterProfile.ID,
+			Bracket:      row.Bracket,
+			Leatherboard: row.Leatherboard,
+			Crawl:        row.Crawl,
+			FetchedAt:    row.FetchedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importGuild return the guild of a row merged into the guild already stored, rows only carrying part of a guild record
+func importGuild(store Store, row models.ProfileRow) models.Guild {
+	guild := models.Guild{
+		ID:   int(row.GuildID),
+		Raid: row.GuildRaid,
+	}
+	if stored, err := store.ReadGuild(row.GuildRaid, int(row.GuildID)); err == nil {
+		guild = *stored
+	}
+	if row.Guild != "" {
+		guild.Name = row.Guild
+	}
+	if row.GuildRealm != "" {
+		guild.Realm = row.GuildRealm
+	}
+	if row.GuildRegionRank > 0 {
+		guild.RegionRank = int(row.GuildRegionRank)
+	}
+	return guild
+}
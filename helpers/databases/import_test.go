@@ -0,0 +1,90 @@
+package databases
+
+import (
+	"strings"
+	"testing"
+	"wowstatistician/models"
+)
+
+func TestCheckImportRow(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  func(row *models.ProfileRow)
+		wantErr string
+	}{
+		{name: "valid row", update: func(row *models.ProfileRow) {}},
+		{name: "source of another db", update: func(row *models.ProfileRow) { row.Source = "raid" }, wantErr: "can not be imported"},
+		{name: "missing region", update: func(row *models.ProfileRow) { row.Region = "" }, wantErr: "no region"},
+		{name: "missing id", update: func(row *models.ProfileRow) { row.ID = 0 }, wantErr: "missing id"},
+		{name: "missing realm id", update: func(row *models.ProfileRow) { row.RealmID = 0 }, wantErr: "realm id"},
+		{name: "missing realm", update: func(row *models.ProfileRow) { row.Realm = "" }, wantErr: "realm id"},
+		{name: "missing class id", update: func(row *models.ProfileRow) { row.ClassID = 0 }, wantErr: "class id"},
+		{name: "missing spec id", update: func(row *models.ProfileRow) { row.SpecID = 0 }, wantErr: "spec id"},
+		{name: "negative item level", update: func(row *models.ProfileRow) { row.EquippedItemLevel = -1 }, wantErr: "negative level"},
+		{name: "more wins than games", update: func(row *models.ProfileRow) { row.Played, row.Won = 1, 2 }, wantErr: "more wins"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			row := testRow(1)
+			row.Source = "arena"
+			row.Region = "eu"
+			test.update(&row)
+			err := checkImportRow(row, "arena")
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("checkImportRow() = %v, want no error", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("checkImportRow() = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportGuild(t *testing.T) {
+	stored := models.Guild{ID: 7, Name: "guild", Realm: "kazzak", Faction: "HORDE", Raid: "nyalotha", Rank: 3, RegionRank: 12, Timestamp: 99}
+	tests := []struct {
+		name   string
+		update func(row *models.ProfileRow)
+		want   models.Guild
+	}{
+		{
+			name:   "row fields override the stored guild",
+			update: func(row *models.ProfileRow) { row.Guild, row.GuildRegionRank = "renamed", 5 },
+			want:   models.Guild{ID: 7, Name: "renamed", Realm: "kazzak", Faction: "HORDE", Raid: "nyalotha", Rank: 3, RegionRank: 5, Timestamp: 99},
+		},
+		{
+			name:   "empty row fields keep the stored guild",
+			update: func(row *models.ProfileRow) {},
+			want:   stored,
+		},
+		{
+			name:   "guild of another raid",
+			update: func(row *models.ProfileRow) { row.GuildRaid, row.Guild = "castle-nathria", "guild" },
+			want:   models.Guild{ID: 7, Name: "guild", Raid: "castle-nathria"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			err := store.WriteGuild(stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			row := testRow(1)
+			row.GuildID = 7
+			row.GuildRaid = "nyalotha"
+			test.update(&row)
+			err = importRow(store, row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			guild, err := store.ReadGuild(row.GuildRaid, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *guild != test.want {
+				t.Errorf("imported guild = %+v, want %+v", *guild, test.want)
+			}
+		})
+	}
+}
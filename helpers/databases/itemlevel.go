@@ -0,0 +1,80 @@
+package databases
+
+import (
+	"sort"
+	"wowstatistician/characters"
+	"wowstatistician/models"
+)
+
+// itemLevelBucketWidth is the item level range of each histogram bucket
+const itemLevelBucketWidth = 5
+
+// itemLevelCounter collect the equipped and average item levels of the players of each spec
+type itemLevelCounter struct {
+	specs    []*models.Spec
+	equipped map[*models.Spec][]int
+	average  map[*models.Spec][]int
+}
+
+func newItemLevelCounter() *itemLevelCounter {
+	return &itemLevelCounter{
+		equipped: map[*models.Spec][]int{},
+		average:  map[*models.Spec][]int{},
+	}
+}
+
+// add collect the item levels of a profile counted in provided spec
+func (c *itemLevelCounter) add(spec *models.Spec, characterProfile *characters.CharacterProfile) {
+	if _, ok := c.equipped[spec]; !ok {
+		c.specs = append(c.specs, spec)
+		c.equipped[spec] = []int{}
+	}
+	if characterProfile.EquippedItemLevel > 0 {
+		c.equipped[spec] = append(c.equipped[spec], characterProfile.EquippedItemLevel)
+	}
+	if characterProfile.AverageItemLevel > 0 {
+		c.average[spec] = append(c.average[spec], characterProfile.AverageItemLevel)
+	}
+}
+
+// apply set the item level summaries of every spec collected
+func (c *itemLevelCounter) apply() {
+	for _, spec := range c.specs {
+		spec.ItemLevel = summarizeItemLevels(c.equipped[spec])
+		spec.AverageItemLevel = summarizeItemLevels(c.average[spec])
+	}
+}
+
+// summarizeItemLevels compute the min, quartiles, 90th percentile, max and histogram of item levels, nil if there are none
+// Percentiles use the nearest rank method so they are always an item level a player has
+func summarizeItemLevels(itemLevels []int) *models.ItemLevels {
+	if len(itemLevels) == 0 {
+		return nil
+	}
+	sort.Ints(itemLevels)
+	summary := &models.ItemLevels{
+		Min:    itemLevels[0],
+		P25:    percentile(itemLevels, 25),
+		Median: percentile(itemLevels, 50),
+		P75:    percentile(itemLevels, 75),
+		P90:    percentile(itemLevels, 90),
+		Max:    itemLevels[len(itemLevels)-1],
+	}
+	for low := summary.Min - summary.Min%itemLevelBucketWidth; low <= summary.Max; low += itemLevelBucketWidth {
+		summary.Histogram = append(summary.Histogram, &models.ItemLevelBucket{ItemLevel: low})
+	}
+	first := summary.Histogram[0].ItemLevel
+	for _, itemLevel := range itemLevels {
+		summary.Histogram[(itemLevel-first)/itemLevelBucketWidth].Count++
+	}
+	return summary
+}
+
+// percentile return the nearest rank percentile of sorted values
+func percentile(sorted []int, percent int) int {
+	rank := (percent*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,125 @@
+package databases
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+	"wowstatistician/helpers"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+// LegacyRegion is the region legacy dbs are imported to by a store opened for every region, as crawls default to it
+const LegacyRegion = "eu"
+
+// legacyKinds list the key prefixes of a legacy per source badger db moved under the scope of a store on import
+var legacyKinds = []string{profilePrefix, ladderPrefix, guildPrefix, memberPrefix, runPrefix, historyPrefix}
+
+// ImportLegacy copy a badger db of the legacy layout, one db per source, into the store scope and return the number of keys imported
+// Profiles are rewritten to the current record version and indexed, including those stored under a bare character ID key, and stats stored under their bare name become snapshots
+// Keys that could not be decoded are logged and skipped
+func (s *BadgerStore) ImportLegacy(path string) (int, error) {
+	legacy, err := OpenDB(path)
+	if err != nil {
+		return 0, errors.New("databases: could not import legacy db - " + err.Error())
+	}
+	defer legacy.Close()
+	batch := s.db.NewWriteBatch()
+	defer batch.Cancel()
+	imported := 0
+	err = legacy.View(func(tnx *badger.Txn) error {
+		iterator := tnx.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			key, data, err := s.legacyKey(item.KeyCopy(nil), data)
+			if err != nil {
+				log.Println("databases: could not import key " + string(item.Key()) + " - " + err.Error())
+				continue
+			}
+			err = batch.Set(key, data)
+			if err != nil {
+				return err
+			}
+			for _, indexKey := range profileIndexKeys(key, data) {
+				err = batch.Set(indexKey, nil)
+				if err != nil {
+					return err
+				}
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.New("databases: could not import legacy db - " + err.Error())
+	}
+	err = batch.Flush()
+	if err != nil {
+		return 0, errors.New("databases: could not import legacy db - " + err.Error())
+	}
+	return imported, nil
+}
+
+// legacyKey return the key and data a key of the legacy layout is imported as
+func (s *BadgerStore) legacyKey(key []byte, data []byte) ([]byte, []byte, error) {
+	if isLegacyProfileKey(key) {
+		data, err := reencodeProfile(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		ID, _ := strconv.Atoi(string(key))
+		return s.scope.ProfileKey("", ID), data, nil
+	}
+	if bytes.HasPrefix(key, []byte(statsPrefix)) {
+		return key, data, nil
+	}
+	for _, kind := range legacyKinds {
+		if !bytes.HasPrefix(key, []byte(kind)) {
+			continue
+		}
+		if kind == profilePrefix && helpers.ProfileDataVersion(data) != helpers.ProfileVersion {
+			var err error
+			data, err = reencodeProfile(data)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return append([]byte(s.scope.prefix(kind)), bytes.TrimPrefix(key, []byte(kind))...), data, nil
+	}
+	stats, err := helpers.DecodeStats(data)
+	if err != nil {
+		return nil, nil, errors.New("unknown key")
+	}
+	if stats.Snapshot == 0 {
+		date, err := time.Parse("01-02-2006", stats.SyncDate)
+		if err == nil {
+			stats.Snapshot = date.Unix()
+		}
+	}
+	data, err = helpers.EncodeStats(*stats)
+	if err != nil {
+		return nil, nil, err
+	}
+	return StatsKey(string(key), stats.Snapshot), data, nil
+}
+
+// isLegacyProfileKey return true for keys made of a bare character ID, as profiles were stored before being namespaced
+func isLegacyProfileKey(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package databases
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"wowstatistician/helpers"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+// writeLegacyDb write a legacy per source badger db holding profiles of provided IDs under both legacy profile key forms
+func writeLegacyDb(t *testing.T, path string, IDs ...int) {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(txn *badger.Txn) error {
+		for i, ID := range IDs {
+			data, err := helpers.EncodeProfile(testProfile(ID, "Mage", "Fire"))
+			if err != nil {
+				return err
+			}
+			key := []byte(strconv.Itoa(ID))
+			if i%2 == 1 {
+				key = []byte(profilePrefix + strconv.Itoa(ID))
+			}
+			err = txn.Set(key, data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportLegacyDb(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		want   string
+	}{
+		{name: "store opened for every region", region: "", want: LegacyRegion},
+		{name: "store opened for a region", region: "us", want: "us"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := testSpec(t, "badger")
+			_, path, _ := parseSpec(spec)
+			writeLegacyDb(t, filepath.Join(path, "raid"), 1, 2)
+			store, err := OpenStore(spec, Scope{Source: "raid", Region: test.region})
+			if err != nil {
+				t.Fatal(err)
+			}
+			regions, err := store.(Regioner).Regions()
+			store.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(regions) != 1 || regions[0] != test.want {
+				t.Errorf("regions = %v, want [%v]", regions, test.want)
+			}
+			if _, err := os.Stat(filepath.Join(path, "raid.legacy")); err != nil {
+				t.Errorf("legacy db not renamed - %v", err)
+			}
+			// Every later generate find the imported profiles, whatever region it is restricted to
+			for _, region := range []string{"", test.want} {
+				stats, err := WriteStatsForDb(spec, "raid", Filter{Region: region})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stats.Overall != 2 {
+					t.Errorf("overall for region %q = %v, want 2", region, stats.Overall)
+				}
+			}
+			conditions, _ := ParseFilterExpression("spec=fire")
+			stats, err := WriteStatsForDb(spec, "raid", Filter{Conditions: conditions})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Overall != 2 {
+				t.Errorf("overall looked up by the spec index = %v, want 2", stats.Overall)
+			}
+		})
+	}
+}
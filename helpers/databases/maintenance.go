@@ -0,0 +1,259 @@
+package databases
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"wowstatistician/helpers"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+// PrefixInfo hold the number of keys sharing a key prefix, their estimated size and the highest version written under it
+type PrefixInfo struct {
+	Prefix  string
+	Keys    int
+	Bytes   int64
+	Version uint64
+}
+
+// Info describe a badger store on disk
+type Info struct {
+	LSMSize   int64
+	VlogSize  int64
+	LastWrite time.Time
+	Prefixes  []PrefixInfo
+}
+
+// CorruptEntry is a record that could not be decoded
+type CorruptEntry struct {
+	Key string
+	Err string
+}
+
+// GcDb compact the badger store of a spec and run value log garbage collection until no value log file is worth rewriting
+// A value log file is rewritten when at least discardRatio of it is stale, it return the store size before and after
+func GcDb(spec string, discardRatio float64) (*Info, *Info, error) {
+	badgerStore, err := openBadgerSpec(spec)
+	if err != nil {
+		return nil, nil, errors.New("databases: could not gc db - " + err.Error())
+	}
+	_, path, _ := parseSpec(spec)
+	before, err := dirInfo(path)
+	if err != nil {
+		badgerStore.Close()
+		return nil, nil, errors.New("databases: could not gc db - " + err.Error())
+	}
+	db := badgerStore.DB()
+	err = db.Flatten(runtime.NumCPU())
+	if err != nil {
+		badgerStore.Close()
+		return nil, nil, errors.New("databases: could not gc db - " + err.Error())
+	}
+	for {
+		err = db.RunValueLogGC(discardRatio)
+		if err == badger.ErrNoRewrite {
+			break
+		}
+		if err != nil {
+			badgerStore.Close()
+			return nil, nil, errors.New("databases: could not gc db - " + err.Error())
+		}
+	}
+	// Sizes are read once the db is closed, as closing is what remove rewritten files
+	err = badgerStore.Close()
+	if err != nil {
+		return nil, nil, errors.New("databases: could not gc db - " + err.Error())
+	}
+	after, err := dirInfo(path)
+	if err != nil {
+		return nil, nil, errors.New("databases: could not gc db - " + err.Error())
+	}
+	return before, after, nil
+}
+
+// InfoDb describe the badger store of a spec: its size on disk, last write and keys by kind and source, ie: profile/arena or stats
+func InfoDb(spec string) (*Info, error) {
+	badgerStore, err := openBadgerSpec(spec)
+	if err != nil {
+		return nil, errors.New("databases: could not read db info - " + err.Error())
+	}
+	defer badgerStore.Close()
+	_, path, _ := parseSpec(spec)
+	info, err := dirInfo(path)
+	if err != nil {
+		return nil, errors.New("databases: could not read db info - " + err.Error())
+	}
+	prefixes := map[string]*PrefixInfo{}
+	err = badgerStore.DB().View(func(tnx *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.PrefetchValues = false
+		iterator := tnx.NewIterator(options)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			prefix := keyPrefix(item.Key())
+			if prefixes[prefix] == nil {
+				prefixes[prefix] = &PrefixInfo{Prefix: prefix}
+			}
+			prefixes[prefix].Keys++
+			prefixes[prefix].Bytes += item.EstimatedSize()
+			if item.Version() > prefixes[prefix].Version {
+				prefixes[prefix].Version = item.Version()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("databases: could not read db info - " + err.Error())
+	}
+	for _, prefix := range prefixes {
+		info.Prefixes = append(info.Prefixes, *prefix)
+	}
+	sort.Slice(info.Prefixes, func(i, j int) bool {
+		return info.Prefixes[i].Prefix < info.Prefixes[j].Prefix
+	})
+	return info, nil
+}
+
+// VerifyDb decode every record of the badger store of a spec and return how many were checked and those that could not be decoded
+// Corrupt records are deleted when remove is true, along with the index keys of corrupt profiles
+// The cross source index key of a character is kept while another of its profiles in the source could be decoded
+func VerifyDb(spec string, remove bool) (int, []CorruptEntry, error) {
+	badgerStore, err := openBadgerSpec(spec)
+	if err != nil {
+		return 0, nil, errors.New("databases: could not verify db - " + err.Error())
+	}
+	defer badgerStore.Close()
+	checked := 0
+	corrupt := []CorruptEntry{}
+	indexed := map[string]bool{}
+	err = badgerStore.DB().View(func(tnx *badger.Txn) error {
+		iterator := tnx.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			decode := recordDecoder(item.Key())
+			if decode == nil {
+				continue
+			}
+			checked++
+			data, err := item.ValueCopy(nil)
+			if err == nil {
+				err = decode(data)
+			}
+			if err != nil {
+				corrupt = append(corrupt, CorruptEntry{Key: string(item.Key()), Err: err.Error()})
+			} else if keys := profileIndexKeys(item.Key(), nil); len(keys) > 0 {
+				indexed[string(keys[0])] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, errors.New("databases: could not verify db - " + err.Error())
+	}
+	if remove && len(corrupt) > 0 {
+		batch := badgerStore.DB().NewWriteBatch()
+		defer batch.Cancel()
+		for _, entry := range corrupt {
+			keys := [][]byte{[]byte(entry.Key)}
+			for _, indexKey := range profileIndexKeys([]byte(entry.Key), nil) {
+				if !indexed[string(indexKey)] {
+					keys = append(keys, indexKey)
+				}
+			}
+			for _, key := range keys {
+				err = batch.Delete(key)
+				if err != nil {
+					return 0, nil, errors.New("databases: could not verify db - " + err.Error())
+				}
+			}
+		}
+		err = batch.Flush()
+		if err != nil {
+			return 0, nil, errors.New("databases: could not verify db - " + err.Error())
+		}
+	}
+	return checked, corrupt, nil
+}
+
+// recordDecoder return the function decoding the records of a key kind, nil for keys holding no encoded record
+func recordDecoder(key []byte) func(data []byte) error {
+	kind := strings.SplitN(string(key), "/", 2)[0] + "/"
+	switch kind {
+	case profilePrefix, historyPrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeProfile(data)
+			return err
+		}
+	case statsPrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeStats(data)
+			return err
+		}
+	case ladderPrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeLadder(data)
+			return err
+		}
+	case guildPrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeGuild(data)
+			return err
+		}
+	case memberPrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeMember(data)
+			return err
+		}
+	case runPrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeRun(data)
+			return err
+		}
+	case provenancePrefix:
+		return func(data []byte) error {
+			_, err := helpers.DecodeProvenance(data)
+			return err
+		}
+	}
+	return nil
+}
+
+// keyPrefix return the kind and source of a key, ie: profile/arena, or its kind alone for stats and store metadata
+func keyPrefix(key []byte) string {
+	kind := strings.SplitN(string(key), "/", 2)[0]
+	source := keySource(key)
+	if source == "" || source == statsSource {
+		return kind
+	}
+	return kind + "/" + source
+}
+
+// dirInfo return the size of the lsm tree and value log files of a badger directory and the time they were last written
+func dirInfo(path string) (*Info, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{}
+	for _, file := range files {
+		switch filepath.Ext(file.Name()) {
+		case ".sst":
+			info.LSMSize += file.Size()
+		case ".vlog":
+			info.VlogSize += file.Size()
+		default:
+			continue
+		}
+		if file.ModTime().After(info.LastWrite) {
+			info.LastWrite = file.ModTime()
+		}
+	}
+	return info, nil
+}
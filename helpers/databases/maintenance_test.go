@@ -0,0 +1,62 @@
+package databases
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVerifyDbRemove(t *testing.T) {
+	spec := testSpec(t, "badger")
+	scope := Scope{Source: "arena", Region: "eu"}
+	store, err := OpenStore(spec, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := map[string][]int{"2v2": {1}, "3v3": {1, 2}}
+	for bracket, IDs := range profiles {
+		for _, ID := range IDs {
+			err = store.WriteProfile(bracket, testProfile(ID, "Mage", "Fire"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, ID := range profiles["3v3"] {
+		err = store.(*BadgerStore).set(scope.ProfileKey("3v3", ID), []byte("corrupt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+	checked, corrupt, err := VerifyDb(spec, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 3 || len(corrupt) != 2 {
+		t.Errorf("checked = %v and corrupt = %v, want 3 and 2", checked, corrupt)
+	}
+	store, err = OpenStore(spec, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	// The character still holding a profile in the source stay indexed, the other one is no longer listed
+	want := map[int][]string{1: {"arena"}, 2: {}}
+	for ID, sources := range want {
+		found, err := store.(Indexer).Sources(ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(found, sources) {
+			t.Errorf("sources of %v = %v, want %v", ID, found, sources)
+		}
+	}
+	conditions, _ := ParseFilterExpression("spec=fire")
+	stats, err := GenerateStatistics(store, Filter{Conditions: conditions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Overall != 1 {
+		t.Errorf("overall = %v, want 1", stats.Overall)
+	}
+}
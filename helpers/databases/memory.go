@@ -0,0 +1,367 @@
+package databases
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"wowstatistician/characters"
+	"wowstatistician/helpers"
+	"wowstatistician/models"
+)
+
+// MemoryStore is a Store kept in memory, mostly useful for tests and one shot computations
+type MemoryStore struct {
+	mutex       sync.RWMutex
+	profiles    map[string]map[int]characters.CharacterProfile
+	ladders     map[string]map[int]models.Ladder
+	guilds      map[string]*models.Guild
+	members     map[int]models.Member
+	runs        map[string]models.Run
+	provenances map[string]map[int]models.Provenance
+	crawls      map[string]int64
+	cutoffs     map[string]int
+	history     map[string]map[int]map[int64][]byte
+	stats       map[string]map[int64][]byte
+}
+
+// NewMemoryStore return an empty in memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		profiles:    map[string]map[int]characters.CharacterProfile{},
+		ladders:     map[string]map[int]models.Ladder{},
+		guilds:      map[string]*models.Guild{},
+		members:     map[int]models.Member{},
+		runs:        map[string]models.Run{},
+		provenances: map[string]map[int]models.Provenance{},
+		crawls:      map[string]int64{},
+		cutoffs:     map[string]int{},
+		history:     map[string]map[int]map[int64][]byte{},
+		stats:       map[string]map[int64][]byte{},
+	}
+}
+
+// Close do nothing as a memory store hold no resource
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// WriteProfile write a character profile, tagged by pvp bracket when provided
+func (s *MemoryStore) WriteProfile(bracket string, characterProfile characters.CharacterProfile) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.profiles[bracket] == nil {
+		s.profiles[bracket] = map[int]characters.CharacterProfile{}
+	}
+	s.profiles[bracket][characterProfile.ID] = characterProfile
+	return nil
+}
+
+// ReadProfile read a character profile provided its pvp bracket, empty if none, and ID
+func (s *MemoryStore) ReadProfile(bracket string, ID int) (*characters.CharacterProfile, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	characterProfile, ok := s.profiles[bracket][ID]
+	if !ok {
+		return nil, errors.New("databases: could not read profile from memory - no profile with id: " + strconv.Itoa(ID))
+	}
+	return &characterProfile, nil
+}
+
+// WriteLadder write the pvp ladder entry a profile was collected from
+func (s *MemoryStore) WriteLadder(ladder models.Ladder) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ladders[ladder.Bracket] == nil {
+		s.ladders[ladder.Bracket] = map[int]models.Ladder{}
+	}
+	s.ladders[ladder.Bracket][ladder.ID] = ladder
+	return nil
+}
+
+// ReadLadder read a pvp ladder entry provided its bracket and character ID
+func (s *MemoryStore) ReadLadder(bracket string, ID int) (*models.Ladder, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	ladder, ok := s.ladders[bracket][ID]
+	if !ok {
+		return nil, errors.New("databases: could not read ladder from memory - no ladder with id: " + strconv.Itoa(ID))
+	}
+	return &ladder, nil
+}
+
+// WriteGuild write a raid hall of fame guild
+func (s *MemoryStore) WriteGuild(guild models.Guild) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.guilds[guildRef(guild.Raid, guild.ID)] = &guild
+	return nil
+}
+
+// ReadGuild read a raid hall of fame guild provided its raid and ID
+func (s *MemoryStore) ReadGuild(raid string, ID int) (*models.Guild, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	guild, ok := s.guilds[guildRef(raid, ID)]
+	if !ok {
+		return nil, errors.New("databases: could not read guild from memory - no guild with id: " + strconv.Itoa(ID))
+	}
+	copied := *guild
+	return &copied, nil
+}
+
+// WriteMember write the guild membership of a character
+func (s *MemoryStore) WriteMember(member models.Member) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.members[member.ID] = member
+	return nil
+}
+
+// WriteRun write a mythic+ run
+func (s *MemoryStore) WriteRun(run models.Run) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.runs[run.ID] = run
+	return nil
+}
+
+// WriteProvenance write where a profile was collected from and when it was fetched
+func (s *MemoryStore) WriteProvenance(provenance models.Provenance) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.provenances[provenance.Bracket] == nil {
+		s.provenances[provenance.Bracket] = map[int]models.Provenance{}
+	}
+	s.provenances[provenance.Bracket][provenance.ID] = provenance
+	return nil
+}
+
+// WriteCrawl record provided crawl as the latest complete crawl of a pvp bracket, empty if none
+func (s *MemoryStore) WriteCrawl(bracket string, crawl int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.crawls[bracket] = crawl
+	return nil
+}
+
+// LatestCrawl return the latest complete crawl of a pvp bracket, empty if none, 0 if no crawl completed
+func (s *MemoryStore) LatestCrawl(bracket string) (int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.crawls[bracket], nil
+}
+
+// WriteCutoff record the rank 1 rating cutoff of a pvp bracket, as published with the season rewards
+func (s *MemoryStore) WriteCutoff(bracket string, rating int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cutoffs[bracket] = rating
+	return nil
+}
+
+// Cutoff return the rank 1 rating cutoff of a pvp bracket, 0 if none was recorded
+func (s *MemoryStore) Cutoff(bracket string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cutoffs[bracket], nil
+}
+
+// WriteProfileSnapshot write a copy of a character profile for provided snapshot, tagged by pvp bracket when provided
+func (s *MemoryStore) WriteProfileSnapshot(snapshot int64, bracket string, characterProfile characters.CharacterProfile) error {
+	data, err := helpers.EncodeProfile(characterProfile)
+	if err != nil {
+		return errors.New("databases: could not write profile snapshot to memory - " + err.Error())
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.history[bracket] == nil {
+		s.history[bracket] = map[int]map[int64][]byte{}
+	}
+	if s.history[bracket][characterProfile.ID] == nil {
+		s.history[bracket][characterProfile.ID] = map[int64][]byte{}
+	}
+	s.history[bracket][characterProfile.ID][snapshot] = data
+	return nil
+}
+
+// ProfileSnapshots read every snapshot copy of a character profile provided its pvp bracket, empty if none, and ID, oldest first
+func (s *MemoryStore) ProfileSnapshots(bracket string, ID int) ([]ProfileSnapshot, error) {
+	s.mutex.RLock()
+	history := s.history[bracket][ID]
+	snapshots := []ProfileSnapshot{}
+	for _, snapshot := range sortedSnapshots(history) {
+		characterProfile, err := helpers.DecodeProfile(history[snapshot])
+		if err != nil {
+			s.mutex.RUnlock()
+			return nil, errors.New("databases: could not read profile snapshots from memory - " + err.Error())
+		}
+		snapshots = append(snapshots, ProfileSnapshot{Snapshot: snapshot, Profile: characterProfile})
+	}
+	s.mutex.RUnlock()
+	return snapshots, nil
+}
+
+// WriteStats append stats under provided name, as the snapshot they carry or as a new one if they carry none
+func (s *MemoryStore) WriteStats(name string, stats models.Stats) error {
+	if stats.Snapshot == 0 {
+		stats.Snapshot = NewSnapshot()
+	}
+	data, err := helpers.EncodeStats(stats)
+	if err != nil {
+		return errors.New("databases: could not write stats to memory - " + err.Error())
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stats[name] == nil {
+		s.stats[name] = map[int64][]byte{}
+	}
+	if _, ok := s.stats[name][stats.Snapshot]; ok {
+		return errors.New("databases: could not write stats to memory - " + errSnapshotExists(name, stats.Snapshot).Error())
+	}
+	s.stats[name][stats.Snapshot] = data
+	return nil
+}
+
+// ReadStats read the latest stats snapshot provided their name
+func (s *MemoryStore) ReadStats(name string) (*models.Stats, error) {
+	snapshots, _ := s.Snapshots(name)
+	if len(snapshots) == 0 {
+		return nil, errors.New("databases: could not read stats from memory - no stats named: " + name)
+	}
+	return s.ReadSnapshot(name, snapshots[len(snapshots)-1])
+}
+
+// ReadSnapshot read a stats snapshot provided their name and snapshot
+func (s *MemoryStore) ReadSnapshot(name string, snapshot int64) (*models.Stats, error) {
+	s.mutex.RLock()
+	data, ok := s.stats[name][snapshot]
+	s.mutex.RUnlock()
+	if !ok {
+		return nil, errors.New("databases: could not read stats from memory - no snapshot " + strconv.FormatInt(snapshot, 10) + " of stats named: " + name)
+	}
+	stats, err := helpers.DecodeStats(data)
+	if err != nil {
+		return nil, errors.New("databases: could not read stats from memory - " + err.Error())
+	}
+	return stats, nil
+}
+
+// Snapshots list the stats snapshots of provided name, oldest first
+func (s *MemoryStore) Snapshots(name string) ([]int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return sortedSnapshots(s.stats[name]), nil
+}
+
+// StatsNames list the names stats have been written under, sorted
+func (s *MemoryStore) StatsNames() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := []string{}
+	for name := range s.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// sortedSnapshots return the snapshots of a snapshot map, oldest first
+func sortedSnapshots(data map[int64][]byte) []int64 {
+	snapshots := []int64{}
+	for snapshot := range data {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i] < snapshots[j]
+	})
+	return snapshots
+}
+
+// Entries call fn for every profile with its collection context, restricted to a pvp bracket or bracket family when provided
+// Profiles are visited ordered by bracket then ID, as a badger store would
+func (s *MemoryStore) Entries(bracket string, fn func(entry Entry) error) error {
+	return s.entries(bracket, fn)
+}
+
+// Runs call fn for every mythic+ run, ordered by ID
+func (s *MemoryStore) Runs(fn func(run models.Run) error) error {
+	s.mutex.RLock()
+	IDs := []string{}
+	for ID := range s.runs {
+		IDs = append(IDs, ID)
+	}
+	sort.Strings(IDs)
+	runs := []models.Run{}
+	for _, ID := range IDs {
+		runs = append(runs, s.runs[ID])
+	}
+	s.mutex.RUnlock()
+	for _, run := range runs {
+		err := fn(run)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entries call fn with a copy of every profile and its collection context, the lock being released between profiles so fn can write to the store
+func (s *MemoryStore) entries(bracket string, fn func(entry Entry) error) error {
+	s.mutex.RLock()
+	brackets := []string{}
+	for name := range s.profiles {
+		if bracket == "" || helpers.MatchBracket(name, []string{bracket}) {
+			brackets = append(brackets, name)
+		}
+	}
+	s.mutex.RUnlock()
+	sort.Strings(brackets)
+	for _, name := range brackets {
+		s.mutex.RLock()
+		IDs := []int{}
+		for ID := range s.profiles[name] {
+			IDs = append(IDs, ID)
+		}
+		s.mutex.RUnlock()
+		sort.Ints(IDs)
+		for _, ID := range IDs {
+			entry, ok := s.entry(name, ID)
+			if !ok {
+				continue
+			}
+			err := fn(entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entry return a copy of a profile with its collection context, false if it was removed meanwhile
+func (s *MemoryStore) entry(bracket string, ID int) (Entry, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	characterProfile, ok := s.profiles[bracket][ID]
+	if !ok {
+		return Entry{}, false
+	}
+	entry := Entry{
+		Profile: &characterProfile,
+	}
+	if ladder, ok := s.ladders[bracket][ID]; ok {
+		entry.Ladder = &ladder
+	}
+	if provenance, ok := s.provenances[bracket][ID]; ok {
+		entry.Provenance = &provenance
+	}
+	if member, ok := s.members[ID]; ok {
+		entry.Member = &member
+		if guild := memberGuild(s.guilds, &member); guild != nil {
+			copied := *guild
+			entry.Guild = &copied
+		}
+	}
+	return entry, true
+}
@@ -0,0 +1,143 @@
+package databases
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"wowstatistician/characters"
+	"wowstatistician/models"
+)
+
+// EndgameSource is the source name stats combining every endgame source are stored under
+const EndgameSource = "endgame"
+
+// EndgameSources list the sources combined in endgame stats
+var EndgameSources = []string{"raid", "mythic", "arena", "rbg"}
+
+// overlapCounter count distinct characters and how many of them each source hold, a character being identified by region and ID
+type overlapCounter struct {
+	seen    map[string]bool
+	sources map[string]int
+}
+
+func newOverlapCounter() *overlapCounter {
+	return &overlapCounter{
+		seen:    map[string]bool{},
+		sources: map[string]int{},
+	}
+}
+
+// add count a character of provided region and ID once, along the sources other than the indexer one holding it
+func (o *overlapCounter) add(indexer Indexer, region string, ID int) error {
+	key := region + "/" + strconv.Itoa(ID)
+	if o.seen[key] {
+		return nil
+	}
+	o.seen[key] = true
+	sources, err := indexer.Sources(ID)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if source != indexer.Scope().Source {
+			o.sources[source]++
+		}
+	}
+	return nil
+}
+
+// result return the number of distinct characters counted and their overlap with every source holding some of them, sorted by source
+func (o *overlapCounter) result() (int, []*models.Overlap) {
+	if len(o.seen) == 0 {
+		return 0, nil
+	}
+	overlaps := []*models.Overlap{}
+	for source, count := range o.sources {
+		overlaps = append(overlaps, &models.Overlap{
+			Source: source,
+			Count:  count,
+			Share:  float64(count) / float64(len(o.seen)),
+		})
+	}
+	sort.Slice(overlaps, func(i, j int) bool {
+		return overlaps[i].Source < overlaps[j].Source
+	})
+	return len(o.seen), overlaps
+}
+
+// endgameCharacter is a character held by one or more endgame sources
+type endgameCharacter struct {
+	profile   *characters.CharacterProfile
+	fetchedAt int64
+	sources   []string
+}
+
+// GenerateEndgameStatistics generate stats of every endgame source of a store combined, counting each character once
+// A character held by several sources is counted from its most recently fetched profile, from the first source holding it when fetch times are unknown
+// Overlaps count how many of the characters each source hold, only the region, latest crawl, query and conditions filters applying across sources
+func GenerateEndgameStatistics(store Store, filter Filter) (*models.Stats, error) {
+	indexer, ok := store.(Indexer)
+	if !ok {
+		return nil, errors.New("databases: could not generate endgame stats - store does not index characters across sources")
+	}
+	if filter.Bracket != "" || filter.MinRating > 0 || filter.Top > 0 || filter.TopGuilds > 0 || filter.RosterRanks > 0 {
+		return nil, errors.New("databases: could not generate endgame stats - only the region, latest crawl, query and conditions filters apply across sources")
+	}
+	keys := []string{}
+	endgame := map[string]*endgameCharacter{}
+	for _, source := range EndgameSources {
+		source := source
+		err := regionsEntries(indexer.WithSource(source), filter, func(view Store, region string, entry Entry) error {
+			if !filter.Accept(entry) {
+				return nil
+			}
+			key := region + "/" + strconv.Itoa(entry.Profile.ID)
+			var fetchedAt int64
+			if entry.Provenance != nil {
+				fetchedAt = entry.Provenance.FetchedAt
+			}
+			character, ok := endgame[key]
+			if !ok {
+				character = &endgameCharacter{profile: entry.Profile, fetchedAt: fetchedAt}
+				endgame[key] = character
+				keys = append(keys, key)
+			} else if fetchedAt > character.fetchedAt {
+				character.profile = entry.Profile
+				character.fetchedAt = fetchedAt
+			}
+			if len(character.sources) == 0 || character.sources[len(character.sources)-1] != source {
+				character.sources = append(character.sources, source)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.New("databases: could not generate endgame stats - " + err.Error())
+		}
+	}
+	stats := &models.Stats{}
+	counts := map[string]int{}
+	itemLevels := newItemLevelCounter()
+	for _, key := range keys {
+		character := endgame[key]
+		spec := stats.Count(character.profile.CharacterClass.Name, character.profile.ActiveSpec.Name)
+		itemLevels.add(spec, character.profile)
+		stats.CountBreakdowns(character.profile.CharacterClass.Name, character.profile.Faction.Type, character.profile.Race.Name, character.profile.ActiveSpec.Role.Type, character.profile.Gender.Type)
+		for _, source := range character.sources {
+			counts[source]++
+		}
+	}
+	itemLevels.apply()
+	stats.ComputeShares()
+	stats.Characters = len(keys)
+	for _, source := range EndgameSources {
+		if counts[source] == 0 {
+			continue
+		}
+		stats.Overlaps = append(stats.Overlaps, &models.Overlap{
+			Source: source,
+			Count:  counts[source],
+			Share:  float64(counts[source]) / float64(len(keys)),
+		})
+	}
+	return stats, nil
+}
@@ -0,0 +1,96 @@
+package databases
+
+import (
+	"strings"
+	"wowstatistician/characters"
+)
+
+// Query select profiles by their indexed fields, empty fields matching any value and values being compared case insensitively
+type Query struct {
+	Class string
+	Spec  string
+	// Realm is a realm slug, ie: kazzak
+	Realm string
+	// Faction is a faction type, ie: HORDE
+	Faction string
+}
+
+// Querier is implemented by stores indexing profiles by class, spec, realm and faction
+type Querier interface {
+	// Query call fn for every profile matching query with its collection context, restricted to a pvp bracket or bracket family when provided
+	Query(bracket string, query Query, fn func(entry Entry) error) error
+}
+
+// indexFields list the profile fields stores index, the first set in a query being the one its matches are looked up by
+var indexFields = []string{"realm", "spec", "class", "faction"}
+
+// Empty return true if the query match every profile
+func (q Query) Empty() bool {
+	return q == Query{}
+}
+
+// Match return true if a profile match every field of the query
+func (q Query) Match(characterProfile *characters.CharacterProfile) bool {
+	values := indexValues(characterProfile)
+	for field, value := range q.values() {
+		if value != "" && values[field] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// indexed return the field and normalized value matches of the query are looked up by
+func (q Query) indexed() (string, string) {
+	values := q.values()
+	for _, field := range indexFields {
+		if values[field] != "" {
+			return field, values[field]
+		}
+	}
+	return "", ""
+}
+
+func (q Query) values() map[string]string {
+	return map[string]string{
+		"class":   indexValue(q.Class),
+		"spec":    indexValue(q.Spec),
+		"realm":   indexValue(q.Realm),
+		"faction": indexValue(q.Faction),
+	}
+}
+
+// indexValues return the normalized values a profile is indexed by, by field
+func indexValues(characterProfile *characters.CharacterProfile) map[string]string {
+	return map[string]string{
+		"class":   indexValue(characterProfile.CharacterClass.Name),
+		"spec":    indexValue(characterProfile.ActiveSpec.Name),
+		"realm":   indexValue(characterProfile.Realm.Slug),
+		"faction": indexValue(characterProfile.Faction.Type),
+	}
+}
+
+// indexReplacer replace the separators of multi-word values so they can be used in keys and filter terms, ie: Beast Mastery as beast-mastery
+var indexReplacer = strings.NewReplacer("/", "-", " ", "-")
+
+// indexValue normalize a value to be compared and used in keys
+func indexValue(value string) string {
+	return strings.ToLower(indexReplacer.Replace(value))
+}
+
+// QueryEntries call fn for every profile of a store matching query, restricted to a pvp bracket or bracket family when provided
+// Stores that are not Querier are scanned
+func QueryEntries(store Store, bracket string, query Query, fn func(entry Entry) error) error {
+	if query.Empty() {
+		return store.Entries(bracket, fn)
+	}
+	if querier, ok := store.(Querier); ok {
+		return querier.Query(bracket, query, fn)
+	}
+	return store.Entries(bracket, func(entry Entry) error {
+		if !query.Match(entry.Profile) {
+			return nil
+		}
+		return fn(entry)
+	})
+}
@@ -0,0 +1,160 @@
+package databases
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"wowstatistician/characters"
+	"wowstatistician/models"
+)
+
+// ProfileSnapshot hold a character profile as it was at a snapshot time
+type ProfileSnapshot struct {
+	Snapshot int64
+	Profile  *characters.CharacterProfile
+}
+
+// NewSnapshot return the snapshot identifier of the current time, a unix timestamp in seconds
+func NewSnapshot() int64 {
+	return time.Now().Unix()
+}
+
+// errSnapshotExists is returned when writing stats under a snapshot already taken, ie: by two generate runs within the same second
+func errSnapshotExists(name string, snapshot int64) error {
+	return errors.New("a snapshot of " + name + " was already taken at " + strconv.FormatInt(snapshot, 10) + ", retry in a second")
+}
+
+// snapshotStore is a Store also writing a dated copy of every profile it writes
+type snapshotStore struct {
+	Store
+	snapshot int64
+}
+
+// WithProfileSnapshots return a store writing, next to every profile, a copy of it for provided snapshot
+func WithProfileSnapshots(store Store, snapshot int64) Store {
+	return &snapshotStore{
+		Store:    store,
+		snapshot: snapshot,
+	}
+}
+
+// WriteProfile write a character profile and its snapshot copy
+func (s *snapshotStore) WriteProfile(bracket string, characterProfile characters.CharacterProfile) error {
+	err := s.Store.WriteProfile(bracket, characterProfile)
+	if err != nil {
+		return err
+	}
+	return s.Store.WriteProfileSnapshot(s.snapshot, bracket, characterProfile)
+}
+
+// ParseSnapshot parse a snapshot provided either as a unix timestamp in seconds or as a 2006-01-02 date
+func ParseSnapshot(value string) (int64, error) {
+	snapshot, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return snapshot, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return 0, errors.New("databases: could not parse snapshot - " + value + " is neither a unix timestamp nor a date")
+	}
+	return date.Unix(), nil
+}
+
+// ParseSnapshotUntil parse the end of a snapshot range, a 2006-01-02 date covering the whole day
+func ParseSnapshotUntil(value string) (int64, error) {
+	snapshot, err := ParseSnapshot(value)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		snapshot += 24*60*60 - 1
+	}
+	return snapshot, nil
+}
+
+// ParseAge parse an age provided either as a number of days, ie: 7d, or as a go duration, ie: 36h
+func ParseAge(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil && days >= 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, errors.New("databases: could not parse age - " + value + " is neither a number of days nor a duration")
+	}
+	return age, nil
+}
+
+// History read the class and spec shares of every stats snapshot of provided name taken between from and to, restricted to a class and a spec when provided
+// A zero from or to leave the range open on that side
+func History(store Store, name string, class string, spec string, from int64, to int64) (*models.History, error) {
+	snapshots, err := store.Snapshots(name)
+	if err != nil {
+		return nil, errors.New("databases: could not read history - " + err.Error())
+	}
+	history := &models.History{
+		Class:  class,
+		Spec:   spec,
+		Points: []*models.HistoryPoint{},
+	}
+	for _, snapshot := range snapshots {
+		if (from != 0 && snapshot < from) || (to != 0 && snapshot > to) {
+			continue
+		}
+		stats, err := store.ReadSnapshot(name, snapshot)
+		if err != nil {
+			return nil, errors.New("databases: could not read history - " + err.Error())
+		}
+		history.Source = stats.Source
+		history.Points = append(history.Points, models.NewHistoryPoint(stats, class, spec))
+	}
+	return history, nil
+}
+
+// Diff read the changes of class and spec shares between two stats snapshots of provided name, highlighting the movers specs whose share moved the most
+// to resolve to the latest snapshot taken at or before it, the latest when zero, and from to the earliest taken at or after it, the one preceding to when zero
+func Diff(store Store, name string, from int64, to int64, movers int) (*models.Diff, error) {
+	snapshots, err := store.Snapshots(name)
+	if err != nil {
+		return nil, errors.New("databases: could not diff snapshots - " + err.Error())
+	}
+	from, to = resolveSnapshots(snapshots, from, to)
+	if from == 0 || to == 0 {
+		return nil, errors.New("databases: could not diff snapshots - not enough snapshots of stats named: " + name + " in the requested range")
+	}
+	fromStats, err := store.ReadSnapshot(name, from)
+	if err != nil {
+		return nil, errors.New("databases: could not diff snapshots - " + err.Error())
+	}
+	toStats, err := store.ReadSnapshot(name, to)
+	if err != nil {
+		return nil, errors.New("databases: could not diff snapshots - " + err.Error())
+	}
+	return models.NewDiff(fromStats, toStats, movers), nil
+}
+
+// resolveSnapshots pick among snapshots, oldest first, the ones to diff for a requested from and to, 0 for a side with no matching snapshot
+func resolveSnapshots(snapshots []int64, from int64, to int64) (int64, int64) {
+	resolvedTo := int64(0)
+	for _, snapshot := range snapshots {
+		if to == 0 || snapshot <= to {
+			resolvedTo = snapshot
+		}
+	}
+	resolvedFrom := int64(0)
+	for _, snapshot := range snapshots {
+		if snapshot >= resolvedTo {
+			break
+		}
+		if from == 0 {
+			resolvedFrom = snapshot
+		} else if snapshot >= from {
+			resolvedFrom = snapshot
+			break
+		}
+	}
+	return resolvedFrom, resolvedTo
+}
@@ -0,0 +1,86 @@
+package databases
+
+import (
+	"testing"
+	"wowstatistician/models"
+)
+
+func TestResolveSnapshots(t *testing.T) {
+	snapshots := []int64{100, 200, 300}
+	tests := []struct {
+		name     string
+		from, to int64
+		wantFrom int64
+		wantTo   int64
+	}{
+		{name: "latest two by default", wantFrom: 200, wantTo: 300},
+		{name: "to between snapshots", to: 250, wantFrom: 100, wantTo: 200},
+		{name: "from between snapshots", from: 150, wantFrom: 200, wantTo: 300},
+		{name: "exact snapshots", from: 100, to: 300, wantFrom: 100, wantTo: 300},
+		{name: "to before every snapshot", to: 50, wantFrom: 0, wantTo: 0},
+		{name: "from after to", from: 250, to: 250, wantFrom: 0, wantTo: 200},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to := resolveSnapshots(snapshots, test.from, test.to)
+			if from != test.wantFrom || to != test.wantTo {
+				t.Errorf("resolveSnapshots(%v, %v) = %v, %v, want %v, %v", test.from, test.to, from, to, test.wantFrom, test.wantTo)
+			}
+		})
+	}
+}
+
+func TestDiffDates(t *testing.T) {
+	store := NewMemoryStore()
+	// 2020-06-01 01:00, 2020-06-08 02:00 and 2020-06-15 00:10 UTC
+	for _, snapshot := range []int64{1590973200, 1591581600, 1592179800} {
+		err := store.WriteStats("arena", models.Stats{Snapshot: snapshot})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom int64
+		wantTo   int64
+		wantErr  bool
+	}{
+		{name: "dates cover whole days", from: "2020-06-01", to: "2020-06-15", wantFrom: 1590973200, wantTo: 1592179800},
+		{name: "dates between snapshots", from: "2020-06-02", to: "2020-06-14", wantErr: true},
+		{name: "from date only", from: "2020-06-02", wantFrom: 1591581600, wantTo: 1592179800},
+		{name: "to date only", to: "2020-06-08", wantFrom: 1590973200, wantTo: 1591581600},
+		{name: "unix timestamps", from: "1590973200", to: "1591581600", wantFrom: 1590973200, wantTo: 1591581600},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to := int64(0), int64(0)
+			var err error
+			if test.from != "" {
+				from, err = ParseSnapshot(test.from)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.to != "" {
+				to, err = ParseSnapshotUntil(test.to)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			diff, err := Diff(store, "arena", from, to, 5)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("diff from %v to %v = %v to %v, want an error", test.from, test.to, diff.From, diff.To)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff.From != test.wantFrom || diff.To != test.wantTo {
+				t.Errorf("diff from %v to %v = %v to %v, want %v to %v", test.from, test.to, diff.From, diff.To, test.wantFrom, test.wantTo)
+			}
+		})
+	}
+}
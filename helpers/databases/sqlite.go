@@ -0,0 +1,823 @@
+package databases
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"wowstatistician/characters"
+	"wowstatistician/common"
+	"wowstatistician/helpers"
+	"wowstatistician/models"
+	"wowstatistician/realms"
+
+	// register the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema is the normalized schema of a sqlite store, every source and region sharing the same file
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS realms (
+	id INTEGER PRIMARY KEY,
+	slug TEXT NOT NULL,
+	name TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS specs (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	class_id INTEGER NOT NULL,
+	class_name TEXT NOT NULL,
+	role TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS characters (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	bracket TEXT NOT NULL,
+	id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	realm_id INTEGER NOT NULL REFERENCES realms(id),
+	spec_id INTEGER NOT NULL REFERENCES specs(id),
+	level INTEGER NOT NULL,
+	gender TEXT NOT NULL,
+	faction TEXT NOT NULL,
+	race_id INTEGER NOT NULL,
+	race_name TEXT NOT NULL,
+	average_item_level INTEGER NOT NULL,
+	equipped_item_level INTEGER NOT NULL,
+	last_login_timestamp INTEGER NOT NULL,
+	PRIMARY KEY (source, region, bracket, id)
+);
+CREATE TABLE IF NOT EXISTS ladder_entries (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	bracket TEXT NOT NULL,
+	character_id INTEGER NOT NULL,
+	season INTEGER NOT NULL,
+	rating INTEGER NOT NULL,
+	rank INTEGER NOT NULL,
+	tier INTEGER NOT NULL,
+	played INTEGER NOT NULL,
+	won INTEGER NOT NULL,
+	lost INTEGER NOT NULL,
+	PRIMARY KEY (source, region, bracket, character_id)
+);
+CREATE TABLE IF NOT EXISTS guilds (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	realm TEXT NOT NULL,
+	faction TEXT NOT NULL,
+	raid TEXT NOT NULL,
+	rank INTEGER NOT NULL,
+	region_rank INTEGER NOT NULL,
+	timestamp INTEGER NOT NULL,
+	PRIMARY KEY (source, region, raid, id)
+);
+CREATE TABLE IF NOT EXISTS members (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	character_id INTEGER NOT NULL,
+	guild_id INTEGER NOT NULL,
+	raid TEXT NOT NULL DEFAULT '',
+	roster_rank INTEGER NOT NULL,
+	PRIMARY KEY (source, region, character_id)
+);
+CREATE TABLE IF NOT EXISTS runs (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	id TEXT NOT NULL,
+	dungeon TEXT NOT NULL,
+	dungeon_id INTEGER NOT NULL,
+	period INTEGER NOT NULL,
+	ranking INTEGER NOT NULL,
+	keystone_level INTEGER NOT NULL,
+	duration INTEGER NOT NULL,
+	completed_timestamp INTEGER NOT NULL,
+	PRIMARY KEY (source, region, id)
+);
+CREATE TABLE IF NOT EXISTS run_members (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	run_id TEXT NOT NULL,
+	character_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	realm TEXT NOT NULL,
+	class TEXT NOT NULL,
+	spec TEXT NOT NULL,
+	PRIMARY KEY (source, region, run_id, character_id)
+);
+CREATE INDEX IF NOT EXISTS characters_region_id ON characters (region, id);
+CREATE INDEX IF NOT EXISTS characters_spec ON characters (source, region, spec_id);
+CREATE INDEX IF NOT EXISTS characters_realm ON characters (source, region, realm_id);
+CREATE INDEX IF NOT EXISTS characters_faction ON characters (source, region, faction COLLATE NOCASE);
+CREATE TABLE IF NOT EXISTS provenances (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	bracket TEXT NOT NULL,
+	character_id INTEGER NOT NULL,
+	leatherboard TEXT NOT NULL,
+	crawl INTEGER NOT NULL,
+	fetched_at INTEGER NOT NULL,
+	PRIMARY KEY (source, region, bracket, character_id)
+);
+CREATE TABLE IF NOT EXISTS crawls (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	bracket TEXT NOT NULL,
+	crawl INTEGER NOT NULL,
+	PRIMARY KEY (source, region, bracket)
+);
+CREATE TABLE IF NOT EXISTS cutoffs (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	bracket TEXT NOT NULL,
+	rating INTEGER NOT NULL,
+	PRIMARY KEY (source, region, bracket)
+);
+CREATE TABLE IF NOT EXISTS snapshots (
+	name TEXT NOT NULL,
+	synced_at INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (name, synced_at)
+);
+CREATE TABLE IF NOT EXISTS profile_snapshots (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	bracket TEXT NOT NULL,
+	character_id INTEGER NOT NULL,
+	snapshot INTEGER NOT NULL,
+	data BLOB NOT NULL,
+	PRIMARY KEY (source, region, bracket, character_id, snapshot)
+);
+`
+
+// SQLiteStore is a Store backed by a sqlite file, scoped to a source and region so every source can share the same file
+type SQLiteStore struct {
+	db    *sql.DB
+	scope Scope
+	// view is true for stores sharing the db of another store, which close it
+	view bool
+}
+
+// OpenSQLiteStore open a sqlite backed store for provided scope at provided file path, creating the schema if needed
+func OpenSQLiteStore(path string, scope Scope) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=10000&_journal_mode=WAL&_foreign_keys=on")
+	if err != nil {
+		return nil, errors.New("databases: could not open sqlite db - " + err.Error())
+	}
+	_, err = db.Exec(sqliteSchema)
+	if err != nil {
+		db.Close()
+		return nil, errors.New("databases: could not open sqlite db - " + err.Error())
+	}
+	err = migrateGuilds(db)
+	if err != nil {
+		db.Close()
+		return nil, errors.New("databases: could not open sqlite db - " + err.Error())
+	}
+	return &SQLiteStore{db: db, scope: scope}, nil
+}
+
+// OpenSQLiteStoreReadOnly open a sqlite backed store at provided path for reading only, ie: to serve its stats while other commands write to it
+func OpenSQLiteStoreReadOnly(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_busy_timeout=10000")
+	if err != nil {
+		return nil, errors.New("databases: could not open sqlite db - " + err.Error())
+	}
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, errors.New("databases: could not open sqlite db - " + err.Error())
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// migrateGuilds key the guilds of a db created before guilds were keyed by raid by their raid, and link their members to it
+func migrateGuilds(db *sql.DB) error {
+	var raid int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('members') WHERE name = 'raid'`).Scan(&raid)
+	if err != nil || raid > 0 {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range []string{
+		`ALTER TABLE guilds RENAME TO guilds_unkeyed`,
+		`CREATE TABLE guilds (
+	source TEXT NOT NULL,
+	region TEXT NOT NULL,
+	id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	realm TEXT NOT NULL,
+	faction TEXT NOT NULL,
+	raid TEXT NOT NULL,
+	rank INTEGER NOT NULL,
+	region_rank INTEGER NOT NULL,
+	timestamp INTEGER NOT NULL,
+	PRIMARY KEY (source, region, raid, id)
+)`,
+		`INSERT INTO guilds SELECT source, region, id, name, realm, faction, raid, rank, region_rank, timestamp FROM guilds_unkeyed`,
+		`DROP TABLE guilds_unkeyed`,
+		`ALTER TABLE members ADD COLUMN raid TEXT NOT NULL DEFAULT ''`,
+		`UPDATE members SET raid = COALESCE((SELECT g.raid FROM guilds g WHERE g.source = members.source AND g.region = members.region AND g.id = members.guild_id), '')`,
+	} {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DB return the underlying sql db
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close close the underlying sql db, unless the store is a view of another store
+func (s *SQLiteStore) Close() error {
+	if s.view {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Regions list the regions profiles or runs of the store source have been written for
+func (s *SQLiteStore) Regions() ([]string, error) {
+	rows, err := s.db.Query(`SELECT region FROM characters WHERE source = ? UNION SELECT region FROM runs WHERE source = ? ORDER BY region`, s.scope.Source, s.scope.Source)
+	if err != nil {
+		return nil, errors.New("databases: could not list regions from sqlite db - " + err.Error())
+	}
+	defer rows.Close()
+	regions := []string{}
+	for rows.Next() {
+		var region string
+		err = rows.Scan(&region)
+		if err != nil {
+			return nil, errors.New("databases: could not list regions from sqlite db - " + err.Error())
+		}
+		regions = append(regions, region)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("databases: could not list regions from sqlite db - " + err.Error())
+	}
+	return regions, nil
+}
+
+// WithRegion return a view of the store scoped to another region of its source, sharing and not closing the underlying db
+func (s *SQLiteStore) WithRegion(region string) Store {
+	return &SQLiteStore{db: s.db, scope: Scope{Source: s.scope.Source, Region: region}, view: true}
+}
+
+// Scope return the source and region the store is scoped to
+func (s *SQLiteStore) Scope() Scope {
+	return s.scope
+}
+
+// Sources list the sources holding a profile of the character of provided ID in the store region, sorted
+func (s *SQLiteStore) Sources(ID int) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT source FROM characters WHERE region = ? AND id = ? ORDER BY source`, s.scope.Region, ID)
+	if err != nil {
+		return nil, errors.New("databases: could not list character sources from sqlite db - " + err.Error())
+	}
+	defer rows.Close()
+	sources := []string{}
+	for rows.Next() {
+		var source string
+		err = rows.Scan(&source)
+		if err != nil {
+			return nil, errors.New("databases: could not list character sources from sqlite db - " + err.Error())
+		}
+		sources = append(sources, source)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("databases: could not list character sources from sqlite db - " + err.Error())
+	}
+	return sources, nil
+}
+
+// WithSource return a view of the store scoped to another source of its region, sharing and not closing the underlying db
+func (s *SQLiteStore) WithSource(source string) Store {
+	return &SQLiteStore{db: s.db, scope: Scope{Source: source, Region: s.scope.Region}, view: true}
+}
+
+// WriteProfile write a character profile, tagged by pvp bracket when provided, along its realm and spec
+func (s *SQLiteStore) WriteProfile(bracket string, characterProfile characters.CharacterProfile) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("databases: could not write profile to sqlite db - " + err.Error())
+	}
+	defer tx.Rollback()
+	// A profile carrying no realm name, ie: an imported one, keep the name already known
+	_, err = tx.Exec(`INSERT INTO realms (id, slug, name) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET slug = excluded.slug, name = CASE WHEN excluded.name = '' THEN realms.name ELSE excluded.name END`,
+		characterProfile.Realm.ID, characterProfile.Realm.Slug, characterProfile.Realm.Name)
+	if err != nil {
+		return errors.New("databases: could not write profile to sqlite db - " + err.Error())
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO specs (id, name, class_id, class_name, role) VALUES (?, ?, ?, ?, ?)`,
+		characterProfile.ActiveSpec.ID, characterProfile.ActiveSpec.Name, characterProfile.CharacterClass.ID, characterProfile.CharacterClass.Name, characterProfile.ActiveSpec.Role.Type)
+	if err != nil {
+		return errors.New("databases: could not write profile to sqlite db - " + err.Error())
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO characters (source, region, bracket, id, name, realm_id, spec_id, level, gender, faction, race_id, race_name, average_item_level, equipped_item_level, last_login_timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, bracket, characterProfile.ID, characterProfile.Name, characterProfile.Realm.ID, characterProfile.ActiveSpec.ID, characterProfile.Level,
+		characterProfile.Gender.Type, characterProfile.Faction.Type, characterProfile.Race.ID, characterProfile.Race.Name,
+		characterProfile.AverageItemLevel, characterProfile.EquippedItemLevel, characterProfile.LastLoginTimestamp)
+	if err != nil {
+		return errors.New("databases: could not write profile to sqlite db - " + err.Error())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("databases: could not write profile to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadProfile read a character profile provided its pvp bracket, empty if none, and ID
+// Only the fields kept by the schema are filled
+func (s *SQLiteStore) ReadProfile(bracket string, ID int) (*characters.CharacterProfile, error) {
+	row := s.db.QueryRow(sqliteEntriesQuery+` WHERE c.source = ? AND c.region = ? AND c.bracket = ? AND c.id = ?`, s.scope.Source, s.scope.Region, bracket, ID)
+	entry, err := scanEntry(row)
+	if err != nil {
+		return nil, errors.New("databases: could not read profile from sqlite db - " + err.Error())
+	}
+	return entry.Profile, nil
+}
+
+// WriteLadder write the pvp ladder entry a profile was collected from
+func (s *SQLiteStore) WriteLadder(ladder models.Ladder) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO ladder_entries (source, region, bracket, character_id, season, rating, rank, tier, played, won, lost)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, ladder.Bracket, ladder.ID, ladder.Season, ladder.Rating, ladder.Rank, ladder.Tier, ladder.Played, ladder.Won, ladder.Lost)
+	if err != nil {
+		return errors.New("databases: could not write ladder to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadLadder read a pvp ladder entry provided its bracket and character ID
+func (s *SQLiteStore) ReadLadder(bracket string, ID int) (*models.Ladder, error) {
+	ladder := &models.Ladder{
+		ID:      ID,
+		Bracket: bracket,
+	}
+	err := s.db.QueryRow(`SELECT season, rating, rank, tier, played, won, lost FROM ladder_entries WHERE source = ? AND region = ? AND bracket = ? AND character_id = ?`, s.scope.Source, s.scope.Region, bracket, ID).
+		Scan(&ladder.Season, &ladder.Rating, &ladder.Rank, &ladder.Tier, &ladder.Played, &ladder.Won, &ladder.Lost)
+	if err != nil {
+		return nil, errors.New("databases: could not read ladder from sqlite db - " + err.Error())
+	}
+	return ladder, nil
+}
+
+// WriteGuild write a raid hall of fame guild
+func (s *SQLiteStore) WriteGuild(guild models.Guild) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO guilds (source, region, id, name, realm, faction, raid, rank, region_rank, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, guild.ID, guild.Name, guild.Realm, guild.Faction, guild.Raid, guild.Rank, guild.RegionRank, guild.Timestamp)
+	if err != nil {
+		return errors.New("databases: could not write guild to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// ReadGuild read a raid hall of fame guild provided its raid and ID
+func (s *SQLiteStore) ReadGuild(raid string, ID int) (*models.Guild, error) {
+	guild := &models.Guild{
+		ID:   ID,
+		Raid: raid,
+	}
+	err := s.db.QueryRow(`SELECT name, realm, faction, rank, region_rank, timestamp FROM guilds WHERE source = ? AND region = ? AND raid = ? AND id = ?`, s.scope.Source, s.scope.Region, raid, ID).
+		Scan(&guild.Name, &guild.Realm, &guild.Faction, &guild.Rank, &guild.RegionRank, &guild.Timestamp)
+	if err != nil {
+		return nil, errors.New("databases: could not read guild from sqlite db - " + err.Error())
+	}
+	return guild, nil
+}
+
+// WriteMember write the guild membership of a character
+func (s *SQLiteStore) WriteMember(member models.Member) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO members (source, region, character_id, guild_id, raid, roster_rank) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, member.ID, member.GuildID, member.Raid, member.RosterRank)
+	if err != nil {
+		return errors.New("databases: could not write member to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// WriteRun write a mythic+ run and its members
+func (s *SQLiteStore) WriteRun(run models.Run) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("databases: could not write run to sqlite db - " + err.Error())
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`INSERT OR REPLACE INTO runs (source, region, id, dungeon, dungeon_id, period, ranking, keystone_level, duration, completed_timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, run.ID, run.Dungeon, run.DungeonID, run.Period, run.Ranking, run.KeystoneLevel, run.Duration, run.CompletedTimestamp)
+	if err != nil {
+		return errors.New("databases: could not write run to sqlite db - " + err.Error())
+	}
+	_, err = tx.Exec(`DELETE FROM run_members WHERE source = ? AND region = ? AND run_id = ?`, s.scope.Source, s.scope.Region, run.ID)
+	if err != nil {
+		return errors.New("databases: could not write run to sqlite db - " + err.Error())
+	}
+	for _, member := range run.Members {
+		_, err = tx.Exec(`INSERT OR REPLACE INTO run_members (source, region, run_id, character_id, name, realm, class, spec) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.scope.Source, s.scope.Region, run.ID, member.ID, member.Name, member.Realm, member.Class, member.Spec)
+		if err != nil {
+			return errors.New("databases: could not write run to sqlite db - " + err.Error())
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("databases: could not write run to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// WriteProvenance write where a profile was collected from and when it was fetched
+func (s *SQLiteStore) WriteProvenance(provenance models.Provenance) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO provenances (source, region, bracket, character_id, leatherboard, crawl, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, provenance.Bracket, provenance.ID, provenance.Leatherboard, provenance.Crawl, provenance.FetchedAt)
+	if err != nil {
+		return errors.New("databases: could not write provenance to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// WriteCrawl record provided crawl as the latest complete crawl of a pvp bracket, empty if none
+func (s *SQLiteStore) WriteCrawl(bracket string, crawl int64) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO crawls (source, region, bracket, crawl) VALUES (?, ?, ?, ?)`, s.scope.Source, s.scope.Region, bracket, crawl)
+	if err != nil {
+		return errors.New("databases: could not write crawl to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// LatestCrawl return the latest complete crawl of a pvp bracket, empty if none, 0 if no crawl completed
+func (s *SQLiteStore) LatestCrawl(bracket string) (int64, error) {
+	var crawl int64
+	err := s.db.QueryRow(`SELECT crawl FROM crawls WHERE source = ? AND region = ? AND bracket = ?`, s.scope.Source, s.scope.Region, bracket).Scan(&crawl)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.New("databases: could not read crawl from sqlite db - " + err.Error())
+	}
+	return crawl, nil
+}
+
+// WriteCutoff record the rank 1 rating cutoff of a pvp bracket, as published with the season rewards
+func (s *SQLiteStore) WriteCutoff(bracket string, rating int) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO cutoffs (source, region, bracket, rating) VALUES (?, ?, ?, ?)`, s.scope.Source, s.scope.Region, bracket, rating)
+	if err != nil {
+		return errors.New("databases: could not write cutoff to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// Cutoff return the rank 1 rating cutoff of a pvp bracket, 0 if none was recorded
+func (s *SQLiteStore) Cutoff(bracket string) (int, error) {
+	var rating int
+	err := s.db.QueryRow(`SELECT rating FROM cutoffs WHERE source = ? AND region = ? AND bracket = ?`, s.scope.Source, s.scope.Region, bracket).Scan(&rating)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.New("databases: could not read cutoff from sqlite db - " + err.Error())
+	}
+	return rating, nil
+}
+
+// WriteProfileSnapshot write a copy of a character profile for provided snapshot, tagged by pvp bracket when provided
+// Snapshot copies are kept as encoded profile records rather than normalized rows
+func (s *SQLiteStore) WriteProfileSnapshot(snapshot int64, bracket string, characterProfile characters.CharacterProfile) error {
+	data, err := helpers.EncodeProfile(characterProfile)
+	if err != nil {
+		return errors.New("databases: could not write profile snapshot to sqlite db - " + err.Error())
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO profile_snapshots (source, region, bracket, character_id, snapshot, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.scope.Source, s.scope.Region, bracket, characterProfile.ID, snapshot, data)
+	if err != nil {
+		return errors.New("databases: could not write profile snapshot to sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// ProfileSnapshots read every snapshot copy of a character profile provided its pvp bracket, empty if none, and ID, oldest first
+func (s *SQLiteStore) ProfileSnapshots(bracket string, ID int) ([]ProfileSnapshot, error) {
+	rows, err := s.db.Query(`SELECT snapshot, data FROM profile_snapshots WHERE source = ? AND region = ? AND bracket = ? AND character_id = ? ORDER BY snapshot`, s.scope.Source, s.scope.Region, bracket, ID)
+	if err != nil {
+		return nil, errors.New("databases: could not read profile snapshots from sqlite db - " + err.Error())
+	}
+	defer rows.Close()
+	snapshots := []ProfileSnapshot{}
+	for rows.Next() {
+		var snapshot int64
+		var data []byte
+		err = rows.Scan(&snapshot, &data)
+		if err != nil {
+			return nil, errors.New("databases: could not read profile snapshots from sqlite db - " + err.Error())
+		}
+		characterProfile, err := helpers.DecodeProfile(data)
+		if err != nil {
+			return nil, errors.New("databases: could not read profile snapshots from sqlite db - " + err.Error())
+		}
+		snapshots = append(snapshots, ProfileSnapshot{Snapshot: snapshot, Profile: characterProfile})
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("databases: could not read profile snapshots from sqlite db - " + err.Error())
+	}
+	return snapshots, nil
+}
+
+// WriteStats append stats under provided name as a json snapshot, as the snapshot they carry or as a new one if they carry none
+func (s *SQLiteStore) WriteStats(name string, stats models.Stats) error {
+	if stats.Snapshot == 0 {
+		stats.Snapshot = NewSnapshot()
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return errors.New("databases: could not write stats to sqlite db - " + err.Error())
+	}
+	result, err := s.db.Exec(`INSERT OR IGNORE INTO snapshots (name, synced_at, data) VALUES (?, ?, ?)`, name, stats.Snapshot, string(data))
+	if err != nil {
+		return errors.New("databases: could not write stats to sqlite db - " + err.Error())
+	}
+	written, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("databases: could not write stats to sqlite db - " + err.Error())
+	}
+	if written == 0 {
+		return errors.New("databases: could not write stats to sqlite db - " + errSnapshotExists(name, stats.Snapshot).Error())
+	}
+	return nil
+}
+
+// ReadStats read the latest stats snapshot provided their name
+func (s *SQLiteStore) ReadStats(name string) (*models.Stats, error) {
+	return s.readStats(`SELECT data FROM snapshots WHERE name = ? ORDER BY synced_at DESC LIMIT 1`, name)
+}
+
+// ReadSnapshot read a stats snapshot provided their name and snapshot
+func (s *SQLiteStore) ReadSnapshot(name string, snapshot int64) (*models.Stats, error) {
+	return s.readStats(`SELECT data FROM snapshots WHERE name = ? AND synced_at = ?`, name, snapshot)
+}
+
+// Snapshots list the stats snapshots of provided name, oldest first
+func (s *SQLiteStore) Snapshots(name string) ([]int64, error) {
+	rows, err := s.db.Query(`SELECT synced_at FROM snapshots WHERE name = ? ORDER BY synced_at`, name)
+	if err != nil {
+		return nil, errors.New("databases: could not list stats snapshots from sqlite db - " + err.Error())
+	}
+	defer rows.Close()
+	snapshots := []int64{}
+	for rows.Next() {
+		var snapshot int64
+		err = rows.Scan(&snapshot)
+		if err != nil {
+			return nil, errors.New("databases: could not list stats snapshots from sqlite db - " + err.Error())
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("databases: could not list stats snapshots from sqlite db - " + err.Error())
+	}
+	return snapshots, nil
+}
+
+// StatsNames list the names stats have been written under, sorted
+func (s *SQLiteStore) StatsNames() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT name FROM snapshots ORDER BY name`)
+	if err != nil {
+		return nil, errors.New("databases: could not list stats names from sqlite db - " + err.Error())
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, errors.New("databases: could not list stats names from sqlite db - " + err.Error())
+		}
+		names = append(names, name)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("databases: could not list stats names from sqlite db - " + err.Error())
+	}
+	return names, nil
+}
+
+// readStats read the json stats snapshot selected by query
+func (s *SQLiteStore) readStats(query string, args ...interface{}) (*models.Stats, error) {
+	var data string
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if err != nil {
+		return nil, errors.New("databases: could not read stats from sqlite db - " + err.Error())
+	}
+	var stats models.Stats
+	err = json.Unmarshal([]byte(data), &stats)
+	if err != nil {
+		return nil, errors.New("databases: could not read stats from sqlite db - " + err.Error())
+	}
+	return &stats, nil
+}
+
+// sqliteEntriesQuery select a profile and its collection context, columns are scanned by scanEntry
+// Members recorded without their raid are joined to the most recent hall of fame entry of their guild, as memberGuild does
+const sqliteEntriesQuery = `SELECT c.bracket, c.id, c.name, c.level, c.gender, c.faction, c.race_id, c.race_name,
+	c.average_item_level, c.equipped_item_level, c.last_login_timestamp,
+	r.id, r.slug, r.name, sp.id, sp.name, sp.class_id, sp.class_name, sp.role,
+	l.season, l.rating, l.rank, l.tier, l.played, l.won, l.lost,
+	m.guild_id, m.raid, m.roster_rank,
+	g.name, g.realm, g.faction, g.raid, g.rank, g.region_rank, g.timestamp,
+	p.leatherboard, p.crawl, p.fetched_at
+	FROM characters c
+	JOIN realms r ON r.id = c.realm_id
+	JOIN specs sp ON sp.id = c.spec_id
+	LEFT JOIN ladder_entries l ON l.source = c.source AND l.region = c.region AND l.bracket = c.bracket AND l.character_id = c.id
+	LEFT JOIN members m ON m.source = c.source AND m.region = c.region AND m.character_id = c.id
+	LEFT JOIN guilds g ON g.source = m.source AND g.region = m.region AND g.id = m.guild_id AND g.raid = CASE WHEN m.raid != '' THEN m.raid ELSE
+		(SELECT latest.raid FROM guilds latest WHERE latest.source = m.source AND latest.region = m.region AND latest.id = m.guild_id ORDER BY latest.timestamp DESC, latest.raid LIMIT 1) END
+	LEFT JOIN provenances p ON p.source = c.source AND p.region = c.region AND p.bracket = c.bracket AND p.character_id = c.id`
+
+// Entries call fn for every profile with its collection context, restricted to a pvp bracket or bracket family when provided
+func (s *SQLiteStore) Entries(bracket string, fn func(entry Entry) error) error {
+	err := s.entries(bracket, fn, ``)
+	if err != nil {
+		return errors.New("databases: could not iterate profiles from sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// Query call fn for every profile matching query with its collection context, restricted to a pvp bracket or bracket family when provided
+func (s *SQLiteStore) Query(bracket string, query Query, fn func(entry Entry) error) error {
+	values := query.values()
+	err := s.entries(bracket, fn, ` AND (? = '' OR `+sqliteIndexValue("sp.class_name")+` = ?) AND (? = '' OR `+sqliteIndexValue("sp.name")+` = ?)
+		AND (? = '' OR `+sqliteIndexValue("r.slug")+` = ?) AND (? = '' OR `+sqliteIndexValue("c.faction")+` = ?)`,
+		values["class"], values["class"], values["spec"], values["spec"], values["realm"], values["realm"], values["faction"], values["faction"])
+	if err != nil {
+		return errors.New("databases: could not query profiles from sqlite db - " + err.Error())
+	}
+	return nil
+}
+
+// sqliteIndexValue return the sql expression normalizing a column as indexValue does
+func sqliteIndexValue(column string) string {
+	return `replace(replace(lower(` + column + `), '/', '-'), ' ', '-')`
+}
+
+// entries call fn for every profile of the store scope, restricted to a pvp bracket or bracket family when provided, matching an additional condition
+// Rows are streamed to fn as they are read, fn writing to the store through another connection of the pool
+func (s *SQLiteStore) entries(bracket string, fn func(entry Entry) error, condition string, args ...interface{}) error {
+	args = append([]interface{}{s.scope.Source, s.scope.Region, bracket, bracket, bracket, bracket}, args...)
+	rows, err := s.db.Query(sqliteEntriesQuery+` WHERE c.source = ? AND c.region = ? AND (? = '' OR c.bracket = ? OR substr(c.bracket, 1, length(?) + 1) = ? || '-')`+condition+` ORDER BY c.bracket, c.id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return err
+		}
+		err = fn(*entry)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Runs call fn for every mythic+ run, ordered by ID
+func (s *SQLiteStore) Runs(fn func(run models.Run) error) error {
+	rows, err := s.db.Query(`SELECT id, dungeon, dungeon_id, period, ranking, keystone_level, duration, completed_timestamp FROM runs WHERE source = ? AND region = ? ORDER BY id`, s.scope.Source, s.scope.Region)
+	if err != nil {
+		return errors.New("databases: could not iterate runs from sqlite db - " + err.Error())
+	}
+	runs := []*models.Run{}
+	for rows.Next() {
+		run := &models.Run{}
+		err := rows.Scan(&run.ID, &run.Dungeon, &run.DungeonID, &run.Period, &run.Ranking, &run.KeystoneLevel, &run.Duration, &run.CompletedTimestamp)
+		if err != nil {
+			rows.Close()
+			return errors.New("databases: could not iterate runs from sqlite db - " + err.Error())
+		}
+		runs = append(runs, run)
+	}
+	err = rows.Close()
+	if err != nil {
+		return errors.New("databases: could not iterate runs from sqlite db - " + err.Error())
+	}
+	for _, run := range runs {
+		members, err := s.db.Query(`SELECT character_id, name, realm, class, spec FROM run_members WHERE source = ? AND region = ? AND run_id = ? ORDER BY character_id`, s.scope.Source, s.scope.Region, run.ID)
+		if err != nil {
+			return errors.New("databases: could not iterate runs from sqlite db - " + err.Error())
+		}
+		for members.Next() {
+			member := &models.RunMember{}
+			err := members.Scan(&member.ID, &member.Name, &member.Realm, &member.Class, &member.Spec)
+			if err != nil {
+				members.Close()
+				return errors.New("databases: could not iterate runs from sqlite db - " + err.Error())
+			}
+			run.Members = append(run.Members, member)
+		}
+		members.Close()
+		err = fn(*run)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEntry scan a row selected by sqliteEntriesQuery to an entry
+func scanEntry(row scanner) (*Entry, error) {
+	var bracket string
+	var ladderSeason, ladderRating, ladderRank, ladderTier, ladderPlayed, ladderWon, ladderLost sql.NullInt64
+	var guildID, rosterRank, guildRank, guildRegionRank, guildTimestamp sql.NullInt64
+	var memberRaid, guildName, guildRealm, guildFaction, guildRaid sql.NullString
+	var leatherboard sql.NullString
+	var crawl, fetchedAt sql.NullInt64
+	characterProfile := &characters.CharacterProfile{}
+	var realm realms.Realm
+	var gender, faction, role string
+	err := row.Scan(&bracket, &characterProfile.ID, &characterProfile.Name, &characterProfile.Level, &gender, &faction,
+		&characterProfile.Race.ID, &characterProfile.Race.Name,
+		&characterProfile.AverageItemLevel, &characterProfile.EquippedItemLevel, &characterProfile.LastLoginTimestamp,
+		&realm.ID, &realm.Slug, &realm.Name,
+		&characterProfile.ActiveSpec.ID, &characterProfile.ActiveSpec.Name, &characterProfile.CharacterClass.ID, &characterProfile.CharacterClass.Name, &role,
+		&ladderSeason, &ladderRating, &ladderRank, &ladderTier, &ladderPlayed, &ladderWon, &ladderLost,
+		&guildID, &memberRaid, &rosterRank,
+		&guildName, &guildRealm, &guildFaction, &guildRaid, &guildRank, &guildRegionRank, &guildTimestamp,
+		&leatherboard, &crawl, &fetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	characterProfile.Realm = realm
+	characterProfile.Gender = common.Value{Type: gender}
+	characterProfile.Faction = common.Value{Type: faction}
+	characterProfile.ActiveSpec.Role = common.Value{Type: role}
+	characterProfile.ActiveSpec.PlayableClass = characterProfile.CharacterClass
+	entry := &Entry{
+		Profile: characterProfile,
+	}
+	if ladderRating.Valid {
+		entry.Ladder = &models.Ladder{
+			ID:      characterProfile.ID,
+			Bracket: bracket,
+			Season:  int(ladderSeason.Int64),
+			Rating:  int(ladderRating.Int64),
+			Rank:    int(ladderRank.Int64),
+			Tier:    int(ladderTier.Int64),
+			Played:  int(ladderPlayed.Int64),
+			Won:     int(ladderWon.Int64),
+			Lost:    int(ladderLost.Int64),
+		}
+	}
+	if guildID.Valid {
+		entry.Member = &models.Member{
+			ID:         characterProfile.ID,
+			GuildID:    int(guildID.Int64),
+			Raid:       memberRaid.String,
+			RosterRank: int(rosterRank.Int64),
+		}
+	}
+	if guildName.Valid {
+		entry.Guild = &models.Guild{
+			ID:         int(guildID.Int64),
+			Name:       guildName.String,
+			Realm:      guildRealm.String,
+			Faction:    guildFaction.String,
+			Raid:       guildRaid.String,
+			Rank:       int(guildRank.Int64),
+			RegionRank: int(guildRegionRank.Int64),
+			Timestamp:  int(guildTimestamp.Int64),
+		}
+	}
+	if leatherboard.Valid {
+		entry.Provenance = &models.Provenance{
+			ID:           characterProfile.ID,
+			Bracket:      bracket,
+			Leatherboard: leatherboard.String,
+			Crawl:        crawl.Int64,
+			FetchedAt:    fetchedAt.Int64,
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,227 @@
+package databases
+
+import (
+	"testing"
+	"wowstatistician/models"
+)
+
+func TestLatestCrawlPerRaid(t *testing.T) {
+	store := NewMemoryStore()
+	members := []struct {
+		ID    int
+		raid  string
+		crawl int64
+	}{
+		{ID: 1, raid: "nyalotha", crawl: 10},
+		{ID: 2, raid: "nyalotha", crawl: 5},
+		{ID: 3, raid: "castle-nathria", crawl: 20},
+	}
+	for _, member := range members {
+		err := store.WriteProfile("", testProfile(member.ID, "Mage", "Fire"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.WriteMember(models.Member{ID: member.ID, GuildID: 7, Raid: member.raid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.WriteProvenance(models.Provenance{ID: member.ID, Leatherboard: member.raid + " hall of fame", Crawl: member.crawl})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Crawling castle nathria after nyalotha keep the members of the latest nyalotha crawl
+	store.WriteCrawl("nyalotha", 10)
+	store.WriteCrawl("castle-nathria", 20)
+	stats, err := GenerateStatistics(store, Filter{LatestCrawl: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Overall != 2 {
+		t.Errorf("overall = %v, want 2", stats.Overall)
+	}
+}
+
+func TestMemberGuildFallback(t *testing.T) {
+	stores := map[string]Store{"memory": NewMemoryStore()}
+	for _, kind := range []string{"badger", "sqlite"} {
+		store, err := OpenStore(testSpec(t, kind), Scope{Source: "raid", Region: "eu"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		stores[kind] = store
+	}
+	for kind, store := range stores {
+		t.Run(kind, func(t *testing.T) {
+			for _, guild := range []models.Guild{
+				{ID: 7, Name: "guild", Raid: "nyalotha", Timestamp: 10},
+				{ID: 7, Name: "guild", Raid: "castle-nathria", Timestamp: 20},
+			} {
+				err := store.WriteGuild(guild)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			members := map[int]string{1: "nyalotha", 2: ""}
+			for ID, raid := range members {
+				err := store.WriteProfile("", testProfile(ID, "Mage", "Fire"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = store.WriteMember(models.Member{ID: ID, GuildID: 7, Raid: raid})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Members recorded without their raid belong to the most recent hall of fame entry of their guild
+			want := map[int]string{1: "nyalotha", 2: "castle-nathria"}
+			err := store.Entries("", func(entry Entry) error {
+				if entry.Guild == nil {
+					t.Errorf("member %v has no guild", entry.Profile.ID)
+				} else if entry.Guild.Raid != want[entry.Profile.ID] {
+					t.Errorf("member %v guild raid = %v, want %v", entry.Profile.ID, entry.Guild.Raid, want[entry.Profile.ID])
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestGenerateStatisticsBracketFamily(t *testing.T) {
+	stores := map[string]Store{"memory": NewMemoryStore()}
+	for _, kind := range []string{"badger", "sqlite"} {
+		store, err := OpenStore(testSpec(t, kind), Scope{Source: "arena", Region: "eu"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		stores[kind] = store
+	}
+	for kind, store := range stores {
+		t.Run(kind, func(t *testing.T) {
+			ladders := []models.Ladder{
+				{ID: 1, Bracket: "shuffle-mage-fire", Played: 10},
+				{ID: 1, Bracket: "shuffle-mage-frost", Played: 20},
+				{ID: 2, Bracket: "shuffle-mage-frost", Played: 30},
+				{ID: 3, Bracket: "3v3", Played: 40},
+			}
+			for _, ladder := range ladders {
+				err := store.WriteProfile(ladder.Bracket, testProfile(ladder.ID, "Mage", "Fire"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = store.WriteLadder(ladder)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			conditions, _ := ParseFilterExpression("class=mage")
+			// A bracket family cover every bracket of the family, counting characters once, whether the profiles are scanned or looked up by an index
+			for _, filter := range []Filter{{Bracket: "shuffle"}, {Bracket: "shuffle", Conditions: conditions}} {
+				stats, err := GenerateStatistics(store, filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stats.Overall != 2 {
+					t.Errorf("overall with conditions %v = %v, want 2", filter.Conditions, stats.Overall)
+				}
+				played := 0
+				if distrib := stats.FindDistribution("Mage"); distrib != nil {
+					for _, spec := range distrib.Specs {
+						played += spec.Played
+					}
+				}
+				if played != 60 {
+					t.Errorf("played with conditions %v = %v, want 60", filter.Conditions, played)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateStatisticsLadderSpec(t *testing.T) {
+	store := NewMemoryStore()
+	ladders := []struct {
+		spec   string
+		ladder models.Ladder
+	}{
+		{spec: "Fire", ladder: models.Ladder{ID: 1, Bracket: "shuffle-mage-fire", Played: 10}},
+		{spec: "Fire", ladder: models.Ladder{ID: 1, Bracket: "shuffle-mage-frost", Played: 20}},
+		{spec: "Frost", ladder: models.Ladder{ID: 2, Bracket: "shuffle-mage-frost", Played: 30}},
+		{spec: "Fire", ladder: models.Ladder{ID: 3, Bracket: "2v2", Played: 40}},
+		{spec: "Arcane", ladder: models.Ladder{ID: 3, Bracket: "3v3", Played: 50}},
+	}
+	for _, ladder := range ladders {
+		err := store.WriteProfile(ladder.ladder.Bracket, testProfile(ladder.ladder.ID, "Mage", ladder.spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.WriteLadder(ladder.ladder)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := GenerateStatistics(store, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Shuffle entries go to the spec their bracket name, other entries to the spec of the profile listed on their bracket
+	distrib := stats.FindDistribution("Mage")
+	for spec, played := range map[string]int{"Fire": 50, "Frost": 50, "Arcane": 50} {
+		found := distrib.FindSpec(spec)
+		if found == nil || found.Played != played {
+			t.Errorf("%v = %+v, want played %v", spec, found, played)
+		}
+	}
+	if arcane := distrib.FindSpec("Arcane"); arcane != nil && arcane.Count != 0 {
+		t.Errorf("arcane count = %v, want 0", arcane.Count)
+	}
+}
+
+func TestGenerateStatisticsBrackets(t *testing.T) {
+	store := NewMemoryStore()
+	ladders := []models.Ladder{
+		{ID: 1, Bracket: "2v2", Rating: 1800, Played: 10, Won: 6, Lost: 4},
+		{ID: 1, Bracket: "3v3", Rating: 2100, Played: 20, Won: 12, Lost: 8},
+		{ID: 2, Bracket: "3v3", Rating: 1500, Played: 5, Won: 1, Lost: 4},
+	}
+	for _, ladder := range ladders {
+		err := store.WriteProfile(ladder.Bracket, testProfile(ladder.ID, "Mage", "Fire"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.WriteLadder(ladder)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		bracket string
+		overall int
+		played  int
+	}{
+		{name: "every bracket count characters once", bracket: "", overall: 2, played: 35},
+		{name: "single bracket", bracket: "3v3", overall: 2, played: 25},
+		{name: "bracket listing one character", bracket: "2v2", overall: 1, played: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stats, err := GenerateStatistics(store, Filter{Bracket: test.bracket})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Overall != test.overall {
+				t.Errorf("overall = %v, want %v", stats.Overall, test.overall)
+			}
+			spec := stats.FindDistribution("Mage").FindSpec("Fire")
+			if spec.Count != test.overall || spec.Played != test.played {
+				t.Errorf("spec count = %v and played = %v, want %v and %v", spec.Count, spec.Played, test.overall, test.played)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// ExportFormats list the formats rows can be exported in
+var ExportFormats = []string{"csv", "jsonl", "parquet"}
+
+// RowWriter stream rows of a single flat struct type, ie: models.ProfileRow, to an output
+type RowWriter interface {
+	// Write write a row
+	Write(row interface{}) error
+	// Close flush buffered rows, leaving the output open
+	Close() error
+}
+
+// NewRowWriter return a writer of rows of the type of provided row to w in provided format
+func NewRowWriter(w io.Writer, format string, row interface{}) (RowWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVRowWriter(w, row)
+	case "jsonl":
+		return &jsonlRowWriter{encoder: json.NewEncoder(w)}, nil
+	case "parquet":
+		parquetWriter, err := writer.NewParquetWriterFromWriter(w, row, 4)
+		if err != nil {
+			return nil, errors.New("export: could not write parquet - " + err.Error())
+		}
+		return &parquetRowWriter{writer: parquetWriter}, nil
+	}
+	return nil, errors.New("export: unknown format: " + format + ", expected one of: " + strings.Join(ExportFormats, ", "))
+}
+
+// csvRowWriter write rows as csv, with a header of their json field names
+type csvRowWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVRowWriter(w io.Writer, row interface{}) (*csvRowWriter, error) {
+	rowType := reflect.Indirect(reflect.ValueOf(row)).Type()
+	header := []string{}
+	for i := 0; i < rowType.NumField(); i++ {
+		header = append(header, strings.Split(rowType.Field(i).Tag.Get("json"), ",")[0])
+	}
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write(header)
+	if err != nil {
+		return nil, errors.New("export: could not write csv - " + err.Error())
+	}
+	return &csvRowWriter{writer: csvWriter}, nil
+}
+
+func (c *csvRowWriter) Write(row interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(row))
+	record := []string{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			record = append(record, field.String())
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			record = append(record, strconv.FormatInt(field.Int(), 10))
+		case reflect.Float32, reflect.Float64:
+			record = append(record, strconv.FormatFloat(field.Float(), 'f', -1, 64))
+		case reflect.Bool:
+			record = append(record, strconv.FormatBool(field.Bool()))
+		default:
+			return errors.New("export: could not write csv - unsupported field kind: " + field.Kind().String())
+		}
+	}
+	err := c.writer.Write(record)
+	if err != nil {
+		return errors.New("export: could not write csv - " + err.Error())
+	}
+	return nil
+}
+
+func (c *csvRowWriter) Close() error {
+	c.writer.Flush()
+	err := c.writer.Error()
+	if err != nil {
+		return errors.New("export: could not write csv - " + err.Error())
+	}
+	return nil
+}
+
+// jsonlRowWriter write rows as json lines
+type jsonlRowWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlRowWriter) Write(row interface{}) error {
+	err := j.encoder.Encode(row)
+	if err != nil {
+		return errors.New("export: could not write json lines - " + err.Error())
+	}
+	return nil
+}
+
+func (j *jsonlRowWriter) Close() error {
+	return nil
+}
+
+// parquetRowWriter write rows as a parquet file, rows being buffered by row group
+type parquetRowWriter struct {
+	writer *writer.ParquetWriter
+}
+
+func (p *parquetRowWriter) Write(row interface{}) error {
+	err := p.writer.Write(row)
+	if err != nil {
+		return errors.New("export: could not write parquet - " + err.Error())
+	}
+	return nil
+}
+
+func (p *parquetRowWriter) Close() error {
+	err := p.writer.WriteStop()
+	if err != nil {
+		return errors.New("export: could not write parquet - " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"wowstatistician/models"
+)
+
+// DiffFormats list the formats a stats diff can be written as
+var DiffFormats = []string{"table", "json", "markdown"}
+
+// WriteDiff write a stats diff to w as a text table, json or a markdown document
+func WriteDiff(w io.Writer, diff *models.Diff, format string) error {
+	var err error
+	switch format {
+	case "table":
+		err = writeDiffTable(w, diff)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	case "markdown":
+		err = writeDiffMarkdown(w, diff)
+	default:
+		return errors.New("format: could not write diff - unknown format: " + format + ", expected one of: " + strings.Join(DiffFormats, ", "))
+	}
+	if err != nil {
+		return errors.New("format: could not write diff - " + err.Error())
+	}
+	return nil
+}
+
+func writeDiffTable(w io.Writer, diff *models.Diff) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "%v: %v -> %v\t%v -> %v players\t%v\n", diff.Source, diffDate(diff.FromDate, diff.From), diffDate(diff.ToDate, diff.To), diff.Overall.From, diff.Overall.To, formatRelative(diff.Overall.Relative))
+	for _, section := range diffSections(diff) {
+		fmt.Fprintf(table, "\n%v\tfrom\tto\tchange\trelative\tfrom share\tto share\tshare change\n", section.title)
+		for _, change := range section.changes {
+			fmt.Fprintf(table, "%v\t%v\t%v\t%+d\t%v\t%v\t%v\t%v\n", changeLabel(change), change.Count.From, change.Count.To, change.Count.Change, formatRelative(change.Count.Relative),
+				formatShare(change.FromShare), formatShare(change.ToShare), formatShareChange(change.ShareChange))
+		}
+	}
+	return table.Flush()
+}
+
+func writeDiffMarkdown(w io.Writer, diff *models.Diff) error {
+	_, err := fmt.Fprintf(w, "# %v: %v -> %v\n\n%v -> %v players (%v)\n", diff.Source, diffDate(diff.FromDate, diff.From), diffDate(diff.ToDate, diff.To), diff.Overall.From, diff.Overall.To, formatRelative(diff.Overall.Relative))
+	if err != nil {
+		return err
+	}
+	for _, section := range diffSections(diff) {
+		fmt.Fprintf(w, "\n## %v\n\n| %v | From | To | Change | Relative | From share | To share | Share change |\n|---|---:|---:|---:|---:|---:|---:|---:|\n", section.title, section.column)
+		for _, change := range section.changes {
+			label := changeLabel(change)
+			if section.highlight {
+				label = "**" + label + "**"
+			}
+			_, err = fmt.Fprintf(w, "| %v | %v | %v | %+d | %v | %v | %v | %v |\n", label, change.Count.From, change.Count.To, change.Count.Change, formatRelative(change.Count.Relative),
+				formatShare(change.FromShare), formatShare(change.ToShare), formatShareChange(change.ShareChange))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type diffSection struct {
+	title     string
+	column    string
+	highlight bool
+	changes   []*models.ShareChange
+}
+
+func diffSections(diff *models.Diff) []diffSection {
+	return []diffSection{
+		{title: "Biggest movers", column: "Spec", highlight: true, changes: diff.Movers},
+		{title: "Classes", column: "Class", changes: diff.Classes},
+		{title: "Specs", column: "Spec", changes: diff.Specs},
+	}
+}
+
+func changeLabel(change *models.ShareChange) string {
+	if change.Spec == "" {
+		return change.Class
+	}
+	return change.Spec + " - " + change.Class
+}
+
+func diffDate(syncDate string, snapshot int64) string {
+	if syncDate == "" {
+		return fmt.Sprint(snapshot)
+	}
+	return fmt.Sprintf("%v (%v)", syncDate, snapshot)
+}
+
+func formatShare(share float64) string {
+	return fmt.Sprintf("%.2f%%", share*100)
+}
+
+func formatShareChange(change float64) string {
+	return fmt.Sprintf("%+.2fpp", change*100)
+}
+
+func formatRelative(relative float64) string {
+	return fmt.Sprintf("%+.1f%%", relative*100)
+}
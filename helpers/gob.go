@@ -4,42 +4,73 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
-	"wowstatistician/characters"
 	"wowstatistician/models"
 )
 
-// EncodeProfile encode a character profile to a byte slice
-func EncodeProfile(characterProfile characters.CharacterProfile) ([]byte, error) {
+// EncodeStats encode a stats map to a byte slice
+func EncodeStats(stats models.Stats) ([]byte, error) {
 	var buffer bytes.Buffer
 	encoder := gob.NewEncoder(&buffer)
-	err := encoder.Encode(characterProfile)
+	err := encoder.Encode(stats)
 	if err != nil {
-		return buffer.Bytes(), errors.New("gob: could not encode profile - " + err.Error())
+		return buffer.Bytes(), errors.New("gob: could not encode stats - " + err.Error())
 	}
 	return buffer.Bytes(), nil
 }
 
-// EncodeStats encode a stats map to a byte slice
-func EncodeStats(stats models.Stats) ([]byte, error) {
+// EncodeLadder encode a ladder entry to a byte slice
+func EncodeLadder(ladder models.Ladder) ([]byte, error) {
 	var buffer bytes.Buffer
 	encoder := gob.NewEncoder(&buffer)
-	err := encoder.Encode(stats)
+	err := encoder.Encode(ladder)
 	if err != nil {
-		return buffer.Bytes(), errors.New("gob: could not encode stats - " + err.Error())
+		return buffer.Bytes(), errors.New("gob: could not encode ladder - " + err.Error())
 	}
 	return buffer.Bytes(), nil
 }
 
-// DecodeProfile decode a byte slice to a character profile
-func DecodeProfile(data []byte) (*characters.CharacterProfile, error) {
-	var characterProfile characters.CharacterProfile
-	buffer := bytes.NewBuffer(data)
-	decoder := gob.NewDecoder(buffer)
-	err := decoder.Decode(&characterProfile)
+// EncodeGuild encode a guild to a byte slice
+func EncodeGuild(guild models.Guild) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := gob.NewEncoder(&buffer)
+	err := encoder.Encode(guild)
+	if err != nil {
+		return buffer.Bytes(), errors.New("gob: could not encode guild - " + err.Error())
+	}
+	return buffer.Bytes(), nil
+}
+
+// EncodeMember encode a guild member to a byte slice
+func EncodeMember(member models.Member) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := gob.NewEncoder(&buffer)
+	err := encoder.Encode(member)
+	if err != nil {
+		return buffer.Bytes(), errors.New("gob: could not encode member - " + err.Error())
+	}
+	return buffer.Bytes(), nil
+}
+
+// EncodeRun encode a mythic+ run to a byte slice
+func EncodeRun(run models.Run) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := gob.NewEncoder(&buffer)
+	err := encoder.Encode(run)
+	if err != nil {
+		return buffer.Bytes(), errors.New("gob: could not encode run - " + err.Error())
+	}
+	return buffer.Bytes(), nil
+}
+
+// EncodeProvenance encode the provenance of a profile to a byte slice
+func EncodeProvenance(provenance models.Provenance) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := gob.NewEncoder(&buffer)
+	err := encoder.Encode(provenance)
 	if err != nil {
-		return nil, errors.New("gob: could not decode profile - " + err.Error())
+		return buffer.Bytes(), errors.New("gob: could not encode provenance - " + err.Error())
 	}
-	return &characterProfile, nil
+	return buffer.Bytes(), nil
 }
 
 // DecodeProfile decode a byte slice to a stats map
@@ -53,3 +84,63 @@ func DecodeStats(data []byte) (*models.Stats, error) {
 	}
 	return &stats, nil
 }
+
+// DecodeLadder decode a byte slice to a ladder entry
+func DecodeLadder(data []byte) (*models.Ladder, error) {
+	var ladder models.Ladder
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	err := decoder.Decode(&ladder)
+	if err != nil {
+		return nil, errors.New("gob: could not decode ladder - " + err.Error())
+	}
+	return &ladder, nil
+}
+
+// DecodeGuild decode a byte slice to a guild
+func DecodeGuild(data []byte) (*models.Guild, error) {
+	var guild models.Guild
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	err := decoder.Decode(&guild)
+	if err != nil {
+		return nil, errors.New("gob: could not decode guild - " + err.Error())
+	}
+	return &guild, nil
+}
+
+// DecodeMember decode a byte slice to a guild member
+func DecodeMember(data []byte) (*models.Member, error) {
+	var member models.Member
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	err := decoder.Decode(&member)
+	if err != nil {
+		return nil, errors.New("gob: could not decode member - " + err.Error())
+	}
+	return &member, nil
+}
+
+// DecodeRun decode a byte slice to a mythic+ run
+func DecodeRun(data []byte) (*models.Run, error) {
+	var run models.Run
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	err := decoder.Decode(&run)
+	if err != nil {
+		return nil, errors.New("gob: could not decode run - " + err.Error())
+	}
+	return &run, nil
+}
+
+// DecodeProvenance decode a byte slice to the provenance of a profile
+func DecodeProvenance(data []byte) (*models.Provenance, error) {
+	var provenance models.Provenance
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	err := decoder.Decode(&provenance)
+	if err != nil {
+		return nil, errors.New("gob: could not decode provenance - " + err.Error())
+	}
+	return &provenance, nil
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ImportFormats list the formats rows can be imported from
+var ImportFormats = []string{"csv", "jsonl"}
+
+// RowReader read rows of a single flat struct type, ie: models.ProfileRow, from an input
+type RowReader interface {
+	// Read fill row with the next row, io.EOF once every row has been read
+	Read(row interface{}) error
+}
+
+// NewRowReader return a reader of rows from r in provided format
+func NewRowReader(r io.Reader, format string) (RowReader, error) {
+	switch format {
+	case "csv":
+		csvReader := csv.NewReader(r)
+		header, err := csvReader.Read()
+		if err != nil {
+			return nil, errors.New("import: could not read csv header - " + err.Error())
+		}
+		return &csvRowReader{reader: csvReader, header: header}, nil
+	case "jsonl":
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		return &jsonlRowReader{decoder: decoder}, nil
+	}
+	return nil, errors.New("import: unknown format: " + format + ", expected one of: " + strings.Join(ImportFormats, ", "))
+}
+
+// csvRowReader read rows from csv, columns being matched to fields by their json name
+type csvRowReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (c *csvRowReader) Read(row interface{}) error {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return err
+	}
+	if err != nil {
+		return errors.New("import: could not read csv - " + err.Error())
+	}
+	value := reflect.Indirect(reflect.ValueOf(row))
+	fields := map[string]reflect.Value{}
+	for i := 0; i < value.NumField(); i++ {
+		fields[strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]] = value.Field(i)
+	}
+	for i, column := range c.header {
+		field, ok := fields[column]
+		if !ok {
+			return errors.New("import: could not read csv - unknown column: " + column)
+		}
+		if i >= len(record) || record[i] == "" {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(record[i])
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			number, err := strconv.ParseInt(record[i], 10, 64)
+			if err != nil {
+				return errors.New("import: could not read csv - column " + column + " is not an integer: " + record[i])
+			}
+			field.SetInt(number)
+		case reflect.Float32, reflect.Float64:
+			number, err := strconv.ParseFloat(record[i], 64)
+			if err != nil {
+				return errors.New("import: could not read csv - column " + column + " is not a number: " + record[i])
+			}
+			field.SetFloat(number)
+		case reflect.Bool:
+			boolean, err := strconv.ParseBool(record[i])
+			if err != nil {
+				return errors.New("import: could not read csv - column " + column + " is not a boolean: " + record[i])
+			}
+			field.SetBool(boolean)
+		default:
+			return errors.New("import: could not read csv - unsupported field kind: " + field.Kind().String())
+		}
+	}
+	return nil
+}
+
+// jsonlRowReader read rows from json lines, rejecting unknown fields
+type jsonlRowReader struct {
+	decoder *json.Decoder
+}
+
+func (j *jsonlRowReader) Read(row interface{}) error {
+	err := j.decoder.Decode(row)
+	if err == io.EOF {
+		return err
+	}
+	if err != nil {
+		return errors.New("import: could not read json lines - " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"wowstatistician/characters"
+	"wowstatistician/common"
+)
+
+// ProfileVersion is the version of the record format profiles are encoded with
+//
+// A record starts with the profileMagic header followed by a single version byte, its fields follow in the order of the version layout
+// Integers are encoded as signed varints and strings as a varint length followed by their bytes
+// Data without header is a legacy version 0 profile, a gob encoded characters.CharacterProfile
+const ProfileVersion = 1
+
+var profileMagic = []byte("WSP")
+
+// profileRecord hold the fields of a character profile stats are computed from
+type profileRecord struct {
+	ID                 int
+	Name               string
+	Level              int
+	RealmID            int
+	RealmSlug          string
+	RealmName          string
+	ClassID            int
+	ClassName          string
+	SpecID             int
+	SpecName           string
+	Role               string
+	Faction            string
+	RaceID             int
+	RaceName           string
+	Gender             string
+	AverageItemLevel   int
+	EquippedItemLevel  int
+	LastLoginTimestamp int
+}
+
+// EncodeProfile encode a character profile to a byte slice, keeping only the fields of the current record version
+func EncodeProfile(characterProfile characters.CharacterProfile) ([]byte, error) {
+	record := profileRecord{
+		ID:                 characterProfile.ID,
+		Name:               characterProfile.Name,
+		Level:              characterProfile.Level,
+		RealmID:            characterProfile.Realm.ID,
+		RealmSlug:          characterProfile.Realm.Slug,
+		RealmName:          characterProfile.Realm.Name,
+		ClassID:            characterProfile.CharacterClass.ID,
+		ClassName:          characterProfile.CharacterClass.Name,
+		SpecID:             characterProfile.ActiveSpec.ID,
+		SpecName:           characterProfile.ActiveSpec.Name,
+		Role:               characterProfile.ActiveSpec.Role.Type,
+		Faction:            characterProfile.Faction.Type,
+		RaceID:             characterProfile.Race.ID,
+		RaceName:           characterProfile.Race.Name,
+		Gender:             characterProfile.Gender.Type,
+		AverageItemLevel:   characterProfile.AverageItemLevel,
+		EquippedItemLevel:  characterProfile.EquippedItemLevel,
+		LastLoginTimestamp: characterProfile.LastLoginTimestamp,
+	}
+	buffer := bytes.NewBuffer(append([]byte{}, profileMagic...))
+	buffer.WriteByte(ProfileVersion)
+	writer := recordWriter{buffer: buffer}
+	writer.writeInt(record.ID)
+	writer.writeString(record.Name)
+	writer.writeInt(record.Level)
+	writer.writeInt(record.RealmID)
+	writer.writeString(record.RealmSlug)
+	writer.writeString(record.RealmName)
+	writer.writeInt(record.ClassID)
+	writer.writeString(record.ClassName)
+	writer.writeInt(record.SpecID)
+	writer.writeString(record.SpecName)
+	writer.writeString(record.Role)
+	writer.writeString(record.Faction)
+	writer.writeInt(record.RaceID)
+	writer.writeString(record.RaceName)
+	writer.writeString(record.Gender)
+	writer.writeInt(record.AverageItemLevel)
+	writer.writeInt(record.EquippedItemLevel)
+	writer.writeInt(record.LastLoginTimestamp)
+	return buffer.Bytes(), nil
+}
+
+// DecodeProfile decode a byte slice of any record version to a character profile
+// Only the fields kept by the record version are filled
+func DecodeProfile(data []byte) (*characters.CharacterProfile, error) {
+	switch version := ProfileDataVersion(data); version {
+	case 0:
+		return decodeProfileV0(data)
+	case 1:
+		return decodeProfileV1(data[len(profileMagic)+1:])
+	default:
+		return nil, errors.New("record: could not decode profile - unknown version: " + strconv.Itoa(version))
+	}
+}
+
+// ProfileDataVersion return the record version a profile byte slice was encoded with, 0 for legacy gob profiles
+func ProfileDataVersion(data []byte) int {
+	if len(data) > len(profileMagic) && bytes.HasPrefix(data, profileMagic) {
+		return int(data[len(profileMagic)])
+	}
+	return 0
+}
+
+// decodeProfileV0 decode a legacy gob encoded character profile
+func decodeProfileV0(data []byte) (*characters.CharacterProfile, error) {
+	var characterProfile characters.CharacterProfile
+	buffer := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buffer)
+	err := decoder.Decode(&characterProfile)
+	if err != nil {
+		return nil, errors.New("gob: could not decode profile - " + err.Error())
+	}
+	return &characterProfile, nil
+}
+
+// decodeProfileV1 decode the fields of a version 1 record
+func decodeProfileV1(data []byte) (*characters.CharacterProfile, error) {
+	reader := recordReader{reader: bytes.NewReader(data)}
+	record := profileRecord{
+		ID:                 reader.readInt(),
+		Name:               reader.readString(),
+		Level:              reader.readInt(),
+		RealmID:            reader.readInt(),
+		RealmSlug:          reader.readString(),
+		RealmName:          reader.readString(),
+		ClassID:            reader.readInt(),
+		ClassName:          reader.readString(),
+		SpecID:             reader.readInt(),
+		SpecName:           reader.readString(),
+		Role:               reader.readString(),
+		Faction:            reader.readString(),
+		RaceID:             reader.readInt(),
+		RaceName:           reader.readString(),
+		Gender:             reader.readString(),
+		AverageItemLevel:   reader.readInt(),
+		EquippedItemLevel:  reader.readInt(),
+		LastLoginTimestamp: reader.readInt(),
+	}
+	if reader.err != nil {
+		return nil, errors.New("record: could not decode profile - " + reader.err.Error())
+	}
+	return record.profile(), nil
+}
+
+// profile return the character profile holding the fields of the record
+func (r profileRecord) profile() *characters.CharacterProfile {
+	characterProfile := &characters.CharacterProfile{
+		ID:                 r.ID,
+		Name:               r.Name,
+		Level:              r.Level,
+		Gender:             common.Value{Type: r.Gender},
+		Faction:            common.Value{Type: r.Faction},
+		AverageItemLevel:   r.AverageItemLevel,
+		EquippedItemLevel:  r.EquippedItemLevel,
+		LastLoginTimestamp: r.LastLoginTimestamp,
+	}
+	characterProfile.Realm.ID = r.RealmID
+	characterProfile.Realm.Slug = r.RealmSlug
+	characterProfile.Realm.Name = r.RealmName
+	characterProfile.CharacterClass.ID = r.ClassID
+	characterProfile.CharacterClass.Name = r.ClassName
+	characterProfile.ActiveSpec.ID = r.SpecID
+	characterProfile.ActiveSpec.Name = r.SpecName
+	characterProfile.ActiveSpec.Role = common.Value{Type: r.Role}
+	characterProfile.ActiveSpec.PlayableClass = characterProfile.CharacterClass
+	characterProfile.Race.ID = r.RaceID
+	characterProfile.Race.Name = r.RaceName
+	return characterProfile
+}
+
+type recordWriter struct {
+	buffer *bytes.Buffer
+}
+
+func (w recordWriter) writeInt(value int) {
+	var data [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(data[:], int64(value))
+	w.buffer.Write(data[:n])
+}
+
+func (w recordWriter) writeString(value string) {
+	w.writeInt(len(value))
+	w.buffer.WriteString(value)
+}
+
+// recordReader read record fields, keeping the first error so fields can be read in sequence and checked once
+type recordReader struct {
+	reader *bytes.Reader
+	err    error
+}
+
+func (r *recordReader) readInt() int {
+	if r.err != nil {
+		return 0
+	}
+	value, err := binary.ReadVarint(r.reader)
+	if err != nil {
+		r.err = err
+		return 0
+	}
+	return int(value)
+}
+
+func (r *recordReader) readString() string {
+	length := r.readInt()
+	if r.err != nil {
+		return ""
+	}
+	if length < 0 || length > r.reader.Len() {
+		r.err = errors.New("invalid string length: " + strconv.Itoa(length))
+		return ""
+	}
+	data := make([]byte, length)
+	_, err := r.reader.Read(data)
+	if err != nil {
+		r.err = err
+		return ""
+	}
+	return string(data)
+}
@@ -155,6 +155,20 @@ type Bracket struct {
 	Type string `json:"type"`
 }
 
+// PvpRewards struct format
+type PvpRewards struct {
+	Links   common.Links `json:"_links"`
+	Season  Season       `json:"season"`
+	Rewards []PvpReward  `json:"rewards"`
+}
+
+// PvpReward struct format, the rank 1 title of a bracket and the rating it is currently awarded at
+type PvpReward struct {
+	Bracket      Bracket      `json:"bracket"`
+	Faction      common.Value `json:"faction"`
+	RatingCutoff int          `json:"rating_cutoff"`
+}
+
 // SeasonMatchStatistics struct format
 type SeasonMatchStatistics struct {
 	Played int `json:"played"`
@@ -450,6 +464,24 @@ func GetPvpLeatherboards(token string, url common.URL) (*PvpLeatherboards, error
 	return &response, nil
 }
 
+// GetPvpRewards return the rank 1 rewards and their rating cutoffs for provided pvp season rewards url
+func GetPvpRewards(token string, url common.URL) (*PvpRewards, error) {
+	authStr := fmt.Sprintf("Bearer %s", token)
+	header := req.Header{
+		"Authorization": authStr,
+	}
+	param := req.Param{
+		"locale": "en_US",
+	}
+	request, err := req.Get(url.Href, header, param)
+	if err != nil {
+		return nil, errors.New("leatherboards: could not retrieve pvp rewards - " + err.Error())
+	}
+	var response PvpRewards
+	request.ToJSON(&response)
+	return &response, nil
+}
+
 // GetPvpLeatherboard return pvp leatherboard for provided pvp leatherboards list url
 func GetPvpLeatherboard(token string, url common.URL) (*PvpLeatherboard, error) {
 	authStr := fmt.Sprintf("Bearer %s", token)
@@ -0,0 +1,15 @@
+package models
+
+// BackupManifest describe a store backup file, it is written next to the backup as <file>.manifest.json
+type BackupManifest struct {
+	// Source is the source the backup is restricted to, ie: raid or stats - empty for the whole store
+	Source string `json:"source,omitempty"`
+	// Since is the version the backup is incremental from, 0 for a full backup
+	Since uint64 `json:"since"`
+	// Version is the highest version backed up, to pass as since to the next incremental backup
+	Version   uint64 `json:"version"`
+	Records   int    `json:"records"`
+	Bytes     int64  `json:"bytes"`
+	SHA256    string `json:"sha256"`
+	CreatedAt int64  `json:"createdat"`
+}
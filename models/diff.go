@@ -0,0 +1,128 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// Diff hold the changes of class and spec shares between two stats snapshots
+type Diff struct {
+	Source   string         `json:"source"`
+	From     int64          `json:"from"`
+	To       int64          `json:"to"`
+	FromDate string         `json:"fromdate"`
+	ToDate   string         `json:"todate"`
+	Overall  *Change        `json:"overall"`
+	Classes  []*ShareChange `json:"classes"`
+	Specs    []*ShareChange `json:"specs"`
+	Movers   []*ShareChange `json:"movers"`
+}
+
+// Change hold a count in both snapshots and its absolute and relative change
+type Change struct {
+	From     int     `json:"from"`
+	To       int     `json:"to"`
+	Change   int     `json:"change"`
+	Relative float64 `json:"relative"`
+}
+
+// ShareChange hold the change of a class, or of a spec of a class, between two snapshots
+// Shares are fractions of the overall player count and ShareChange their difference
+type ShareChange struct {
+	Class       string  `json:"class"`
+	Spec        string  `json:"spec,omitempty"`
+	Count       *Change `json:"count"`
+	FromShare   float64 `json:"fromshare"`
+	ToShare     float64 `json:"toshare"`
+	ShareChange float64 `json:"sharechange"`
+}
+
+// NewDiff return the changes from a stats snapshot to another, movers being the number of specs whose share moved the most to highlight
+func NewDiff(from *Stats, to *Stats, movers int) *Diff {
+	diff := &Diff{
+		Source:   to.Source,
+		From:     from.Snapshot,
+		To:       to.Snapshot,
+		FromDate: from.SyncDate,
+		ToDate:   to.SyncDate,
+		Overall:  newChange(from.Overall, to.Overall),
+		Classes:  []*ShareChange{},
+		Specs:    []*ShareChange{},
+		Movers:   []*ShareChange{},
+	}
+	for _, key := range diffKeys(from, to) {
+		fromCount, toCount := countOf(from, key[0], key[1]), countOf(to, key[0], key[1])
+		change := &ShareChange{
+			Class:     key[0],
+			Spec:      key[1],
+			Count:     newChange(fromCount, toCount),
+			FromShare: share(fromCount, from.Overall),
+			ToShare:   share(toCount, to.Overall),
+		}
+		change.ShareChange = change.ToShare - change.FromShare
+		if change.Spec == "" {
+			diff.Classes = append(diff.Classes, change)
+		} else {
+			diff.Specs = append(diff.Specs, change)
+		}
+	}
+	diff.Movers = append(diff.Movers, diff.Specs...)
+	sort.SliceStable(diff.Movers, func(i, j int) bool {
+		return math.Abs(diff.Movers[i].ShareChange) > math.Abs(diff.Movers[j].ShareChange)
+	})
+	if len(diff.Movers) > movers {
+		diff.Movers = diff.Movers[:movers]
+	}
+	return diff
+}
+
+// diffKeys return the class and spec pairs of both stats, a class alone having an empty spec, ordered as first seen
+func diffKeys(from *Stats, to *Stats) [][2]string {
+	keys := [][2]string{}
+	seen := map[[2]string]bool{}
+	for _, stats := range []*Stats{from, to} {
+		for _, distribution := range stats.Distributions {
+			key := [2]string{distribution.Class, ""}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+			for _, spec := range distribution.Specs {
+				key := [2]string{distribution.Class, spec.Spec}
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// countOf return the count of a class, or of a spec of a class, 0 if absent
+func countOf(stats *Stats, class string, spec string) int {
+	distribution := stats.FindDistribution(class)
+	if distribution == nil {
+		return 0
+	}
+	if spec == "" {
+		return distribution.Total
+	}
+	found := distribution.FindSpec(spec)
+	if found == nil {
+		return 0
+	}
+	return found.Count
+}
+
+func newChange(from int, to int) *Change {
+	change := &Change{
+		From:   from,
+		To:     to,
+		Change: to - from,
+	}
+	if from != 0 {
+		change.Relative = float64(to-from) / float64(from)
+	}
+	return change
+}
@@ -0,0 +1,75 @@
+package models
+
+// ProfileRow is the flat export row of a character profile with the context it was collected from
+type ProfileRow struct {
+	Source            string `json:"source" parquet:"name=source, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Region            string `json:"region" parquet:"name=region, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Bracket           string `json:"bracket" parquet:"name=bracket, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ID                int64  `json:"id" parquet:"name=id, type=INT64"`
+	Name              string `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Realm             string `json:"realm" parquet:"name=realm, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RealmID           int64  `json:"realmid" parquet:"name=realmid, type=INT64"`
+	Faction           string `json:"faction" parquet:"name=faction, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Race              string `json:"race" parquet:"name=race, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Gender            string `json:"gender" parquet:"name=gender, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Class             string `json:"class" parquet:"name=class, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ClassID           int64  `json:"classid" parquet:"name=classid, type=INT64"`
+	Spec              string `json:"spec" parquet:"name=spec, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SpecID            int64  `json:"specid" parquet:"name=specid, type=INT64"`
+	Role              string `json:"role" parquet:"name=role, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Level             int64  `json:"level" parquet:"name=level, type=INT64"`
+	AverageItemLevel  int64  `json:"averageitemlevel" parquet:"name=averageitemlevel, type=INT64"`
+	EquippedItemLevel int64  `json:"equippeditemlevel" parquet:"name=equippeditemlevel, type=INT64"`
+	LastLogin         int64  `json:"lastlogin" parquet:"name=lastlogin, type=INT64"`
+	Season            int64  `json:"season" parquet:"name=season, type=INT64"`
+	Rating            int64  `json:"rating" parquet:"name=rating, type=INT64"`
+	Rank              int64  `json:"rank" parquet:"name=rank, type=INT64"`
+	Played            int64  `json:"played" parquet:"name=played, type=INT64"`
+	Won               int64  `json:"won" parquet:"name=won, type=INT64"`
+	Lost              int64  `json:"lost" parquet:"name=lost, type=INT64"`
+	GuildID           int64  `json:"guildid" parquet:"name=guildid, type=INT64"`
+	Guild             string `json:"guild" parquet:"name=guild, type=BYTE_ARRAY, convertedtype=UTF8"`
+	GuildRealm        string `json:"guildrealm" parquet:"name=guildrealm, type=BYTE_ARRAY, convertedtype=UTF8"`
+	GuildRegionRank   int64  `json:"guildregionrank" parquet:"name=guildregionrank, type=INT64"`
+	GuildRaid         string `json:"guildraid" parquet:"name=guildraid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RosterRank        int64  `json:"rosterrank" parquet:"name=rosterrank, type=INT64"`
+	Leatherboard      string `json:"leatherboard" parquet:"name=leatherboard, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Crawl             int64  `json:"crawl" parquet:"name=crawl, type=INT64"`
+	FetchedAt         int64  `json:"fetchedat" parquet:"name=fetchedat, type=INT64"`
+}
+
+// StatsRow is the flat export row of a spec of a stats snapshot
+type StatsRow struct {
+	Name     string  `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Snapshot int64   `json:"snapshot" parquet:"name=snapshot, type=INT64"`
+	SyncDate string  `json:"syncdate" parquet:"name=syncdate, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Source   string  `json:"source" parquet:"name=source, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Region   string  `json:"region" parquet:"name=region, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Bracket  string  `json:"bracket" parquet:"name=bracket, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Class    string  `json:"class" parquet:"name=class, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Spec     string  `json:"spec" parquet:"name=spec, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Count    int64   `json:"count" parquet:"name=count, type=INT64"`
+	Overall  int64   `json:"overall" parquet:"name=overall, type=INT64"`
+	Share    float64 `json:"share" parquet:"name=share, type=DOUBLE"`
+	Played   int64   `json:"played" parquet:"name=played, type=INT64"`
+	Won      int64   `json:"won" parquet:"name=won, type=INT64"`
+	Lost     int64   `json:"lost" parquet:"name=lost, type=INT64"`
+	WinRate  float64 `json:"winrate" parquet:"name=winrate, type=DOUBLE"`
+}
+
+// RunRow is the flat export row of a member of a mythic+ run
+type RunRow struct {
+	Region             string `json:"region" parquet:"name=region, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RunID              string `json:"runid" parquet:"name=runid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Dungeon            string `json:"dungeon" parquet:"name=dungeon, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Period             int64  `json:"period" parquet:"name=period, type=INT64"`
+	Ranking            int64  `json:"ranking" parquet:"name=ranking, type=INT64"`
+	KeystoneLevel      int64  `json:"keystonelevel" parquet:"name=keystonelevel, type=INT64"`
+	Duration           int64  `json:"duration" parquet:"name=duration, type=INT64"`
+	CompletedTimestamp int64  `json:"completedtimestamp" parquet:"name=completedtimestamp, type=INT64"`
+	MemberID           int64  `json:"memberid" parquet:"name=memberid, type=INT64"`
+	Name               string `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Realm              string `json:"realm" parquet:"name=realm, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Class              string `json:"class" parquet:"name=class, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Spec               string `json:"spec" parquet:"name=spec, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
@@ -0,0 +1,39 @@
+package models
+
+// Guild hold the raid hall of fame entry of a guild
+type Guild struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Realm      string `json:"realm"`
+	Faction    string `json:"faction"`
+	Raid       string `json:"raid"`
+	Rank       int    `json:"rank"`
+	RegionRank int    `json:"regionrank"`
+	Timestamp  int    `json:"timestamp"`
+}
+
+// Member link a character profile to the guild and raid hall of fame it was collected from and its rank in the guild roster, 0 being guild master
+type Member struct {
+	ID         int    `json:"id"`
+	GuildID    int    `json:"guildid"`
+	Raid       string `json:"raid,omitempty"`
+	RosterRank int    `json:"rosterrank"`
+}
+
+// GuildStats hold the class repartition of a single guild
+type GuildStats struct {
+	Guild         string          `json:"guild"`
+	Realm         string          `json:"realm"`
+	RegionRank    int             `json:"regionrank"`
+	Timestamp     int             `json:"timestamp"`
+	Overall       int             `json:"overall"`
+	Distributions []*Distribution `json:"distributions"`
+}
+
+// Count add a player of provided class and spec to the guild stats and return its spec
+func (g *GuildStats) Count(class string, spec string) *Spec {
+	var found *Spec
+	g.Distributions, found = count(g.Distributions, class, spec)
+	g.Overall++
+	return found
+}
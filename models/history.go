@@ -0,0 +1,66 @@
+package models
+
+// History hold the class and spec shares of a stats db over its snapshots
+type History struct {
+	Source string          `json:"source"`
+	Class  string          `json:"class,omitempty"`
+	Spec   string          `json:"spec,omitempty"`
+	Points []*HistoryPoint `json:"points"`
+}
+
+// HistoryPoint hold the class and spec shares of a single stats snapshot
+type HistoryPoint struct {
+	Snapshot int64    `json:"snapshot"`
+	SyncDate string   `json:"syncdate"`
+	Overall  int      `json:"overall"`
+	Classes  []*Share `json:"classes"`
+	Specs    []*Share `json:"specs"`
+}
+
+// Share hold the count of a class, or of a spec of a class, and its share of the overall player count
+type Share struct {
+	Class string  `json:"class"`
+	Spec  string  `json:"spec,omitempty"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// NewHistoryPoint return the shares of stats, restricted to a class and a spec when provided
+func NewHistoryPoint(stats *Stats, class string, spec string) *HistoryPoint {
+	point := &HistoryPoint{
+		Snapshot: stats.Snapshot,
+		SyncDate: stats.SyncDate,
+		Overall:  stats.Overall,
+		Classes:  []*Share{},
+		Specs:    []*Share{},
+	}
+	for _, distribution := range stats.Distributions {
+		if class != "" && distribution.Class != class {
+			continue
+		}
+		point.Classes = append(point.Classes, &Share{
+			Class: distribution.Class,
+			Count: distribution.Total,
+			Share: share(distribution.Total, stats.Overall),
+		})
+		for _, v := range distribution.Specs {
+			if spec != "" && v.Spec != spec {
+				continue
+			}
+			point.Specs = append(point.Specs, &Share{
+				Class: distribution.Class,
+				Spec:  v.Spec,
+				Count: v.Count,
+				Share: share(v.Count, stats.Overall),
+			})
+		}
+	}
+	return point
+}
+
+func share(count int, overall int) float64 {
+	if overall == 0 {
+		return 0
+	}
+	return float64(count) / float64(overall)
+}
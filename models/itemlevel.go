@@ -0,0 +1,18 @@
+package models
+
+// ItemLevels summarize the item levels of the players of a spec, players without an item level being left out
+type ItemLevels struct {
+	Min       int                `json:"min"`
+	P25       int                `json:"p25"`
+	Median    int                `json:"median"`
+	P75       int                `json:"p75"`
+	P90       int                `json:"p90"`
+	Max       int                `json:"max"`
+	Histogram []*ItemLevelBucket `json:"histogram"`
+}
+
+// ItemLevelBucket hold the number of players whose item level is at least ItemLevel and below the next bucket
+type ItemLevelBucket struct {
+	ItemLevel int `json:"itemlevel"`
+	Count     int `json:"count"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Ladder hold the pvp leatherboard entry a character profile was collected from
+type Ladder struct {
+	ID      int    `json:"id"`
+	Bracket string `json:"bracket"`
+	Season  int    `json:"season"`
+	Rating  int    `json:"rating"`
+	Rank    int    `json:"rank"`
+	Tier    int    `json:"tier"`
+	Played  int    `json:"played"`
+	Won     int    `json:"won"`
+	Lost    int    `json:"lost"`
+}
+
+// RatingBucket hold the number of players at or above a rating, with the class and spec they play
+type RatingBucket struct {
+	Label         string          `json:"label"`
+	Rating        int             `json:"rating"`
+	Overall       int             `json:"overall"`
+	Distributions []*Distribution `json:"distributions,omitempty"`
+}
+
+// Count add a player of provided class and spec to the rating bucket
+func (b *RatingBucket) Count(class string, spec string) {
+	b.Distributions, _ = count(b.Distributions, class, spec)
+	b.Overall++
+}
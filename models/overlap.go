@@ -0,0 +1,8 @@
+package models
+
+// Overlap count how many characters of stats another source also hold a profile of
+type Overlap struct {
+	Source string  `json:"source"`
+	Count  int     `json:"count"`
+	Share  float64 `json:"share"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Provenance record where a character profile was collected from and when it was last fetched
+type Provenance struct {
+	ID      int    `json:"id"`
+	Bracket string `json:"bracket,omitempty"`
+	// Leatherboard describe the leatherboard the profile was listed on, ie: 3v3 season 29 or nyalotha-the-waking-city hall of fame
+	Leatherboard string `json:"leatherboard"`
+	// Crawl is the retreive run the profile was last listed by, a unix timestamp of its start
+	Crawl     int64 `json:"crawl"`
+	FetchedAt int64 `json:"fetchedat"`
+}
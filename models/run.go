@@ -0,0 +1,23 @@
+package models
+
+// Run hold a mythic+ leading group run
+type Run struct {
+	ID                 string       `json:"id"`
+	Dungeon            string       `json:"dungeon"`
+	DungeonID          int          `json:"dungeonid"`
+	Period             int          `json:"period"`
+	Ranking            int          `json:"ranking"`
+	KeystoneLevel      int          `json:"keystonelevel"`
+	Duration           int          `json:"duration"`
+	CompletedTimestamp int          `json:"completedtimestamp"`
+	Members            []*RunMember `json:"members"`
+}
+
+// RunMember hold a character taking part in a mythic+ run
+type RunMember struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Realm string `json:"realm"`
+	Class string `json:"class"`
+	Spec  string `json:"spec"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "math"
+
+// shareZ is the standard normal quantile of the 95% confidence intervals of shares
+const shareZ = 1.96
+
+// ComputeShares set the share of the overall player count of every class and spec, with its 95% Wilson score interval, guilds and rating buckets included
+// Small samples get wide intervals, so a share computed from a handful of players is not read as more meaningful than it is
+func (s *Stats) ComputeShares() {
+	computeShares(s.Distributions, s.Overall)
+	for _, guild := range s.Guilds {
+		computeShares(guild.Distributions, guild.Overall)
+	}
+	for _, bucket := range s.Ratings {
+		computeShares(bucket.Distributions, bucket.Overall)
+	}
+}
+
+func computeShares(distributions []*Distribution, overall int) {
+	for _, distribution := range distributions {
+		distribution.Share = share(distribution.Total, overall)
+		distribution.ShareLow, distribution.ShareHigh = wilson(distribution.Total, overall)
+		for _, spec := range distribution.Specs {
+			spec.Share = share(spec.Count, overall)
+			spec.ShareLow, spec.ShareHigh = wilson(spec.Count, overall)
+		}
+	}
+}
+
+// wilson return the bounds of the Wilson score interval of count successes out of overall trials
+func wilson(count int, overall int) (float64, float64) {
+	if overall == 0 {
+		return 0, 0
+	}
+	n := float64(overall)
+	p := float64(count) / n
+	z2 := shareZ * shareZ
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	margin := shareZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / (1 + z2/n)
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
@@ -1,21 +1,64 @@
 package models
 
 type Stats struct {
+	Snapshot      int64           `json:"snapshot"`
 	SyncDate      string          `json:"syncdate"`
 	Source        string          `json:"source"`
+	Region        string          `json:"region,omitempty"`
+	Bracket       string          `json:"bracket,omitempty"`
+	MinRating     int             `json:"minrating,omitempty"`
+	Top           int             `json:"top,omitempty"`
+	TopGuilds     int             `json:"topguilds,omitempty"`
+	RosterRanks   int             `json:"rosterranks,omitempty"`
+	LatestCrawl   bool            `json:"latestcrawl,omitempty"`
+	Filter        string          `json:"filter,omitempty"`
 	Overall       int             `json:"overall"`
+	Characters    int             `json:"characters,omitempty"`
+	Overlaps      []*Overlap      `json:"overlaps,omitempty"`
 	Distributions []*Distribution `json:"distributions"`
+	Factions      []*Breakdown    `json:"factions,omitempty"`
+	Races         []*Breakdown    `json:"races,omitempty"`
+	Roles         []*Breakdown    `json:"roles,omitempty"`
+	Genders       []*Breakdown    `json:"genders,omitempty"`
+	Ratings       []*RatingBucket `json:"ratings,omitempty"`
+	Guilds        []*GuildStats   `json:"guilds,omitempty"`
 }
 
 type Distribution struct {
-	Class string  `json:"class"`
-	Total int     `json:"total"`
-	Specs []*Spec `json:"specs"`
+	Class string `json:"class"`
+	Total int    `json:"total"`
+	// Share is the class Total over the overall player count, ShareLow and ShareHigh bounding it with a 95% confidence interval
+	Share     float64      `json:"share"`
+	ShareLow  float64      `json:"sharelow"`
+	ShareHigh float64      `json:"sharehigh"`
+	Specs     []*Spec      `json:"specs"`
+	Races     []*Breakdown `json:"races,omitempty"`
+}
+
+// Breakdowns name the profile fields stats are broken down by besides class and spec
+var Breakdowns = []string{"faction", "race", "role", "gender"}
+
+// Breakdown count the players sharing a value of a profile field, ie: HORDE for faction
+type Breakdown struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
 type Spec struct {
 	Spec  string `json:"spec"`
 	Count int    `json:"count"`
+	// Share is the spec Count over the overall player count, not over its class Total, so the specs of a class add up to the class Share
+	// ShareLow and ShareHigh are the bounds of its 95% confidence interval
+	Share     float64 `json:"share"`
+	ShareLow  float64 `json:"sharelow"`
+	ShareHigh float64 `json:"sharehigh"`
+	Played    int     `json:"played,omitempty"`
+	Won       int     `json:"won,omitempty"`
+	Lost      int     `json:"lost,omitempty"`
+	WinRate   float64 `json:"winrate,omitempty"`
+	// ItemLevel summarize the equipped item levels of the spec players, AverageItemLevel their average item levels
+	ItemLevel        *ItemLevels `json:"itemlevel,omitempty"`
+	AverageItemLevel *ItemLevels `json:"averageitemlevel,omitempty"`
 }
 
 func (s *Stats) FindDistribution(class string) *Distribution {
@@ -35,3 +78,106 @@ func (d *Distribution) FindSpec(spec string) *Spec {
 	}
 	return nil
 }
+
+// Count add a player of provided class and spec to the stats and return its spec
+func (s *Stats) Count(class string, spec string) *Spec {
+	var found *Spec
+	s.Distributions, found = count(s.Distributions, class, spec)
+	s.Overall++
+	return found
+}
+
+// AddSpec return the spec of provided class, added without any player when none was counted yet
+func (s *Stats) AddSpec(class string, spec string) *Spec {
+	distrib := s.FindDistribution(class)
+	if distrib == nil {
+		distrib = &Distribution{
+			Class: class,
+		}
+		s.Distributions = append(s.Distributions, distrib)
+	}
+	found := distrib.FindSpec(spec)
+	if found == nil {
+		found = &Spec{
+			Spec: spec,
+		}
+		distrib.Specs = append(distrib.Specs, found)
+	}
+	return found
+}
+
+// CountBreakdowns add a player of provided class, faction, race, role and gender to the stats breakdowns, empty values being left out
+// The race is also counted in the distribution of the class, which must have been counted first
+func (s *Stats) CountBreakdowns(class string, faction string, race string, role string, gender string) {
+	s.Factions = countBreakdown(s.Factions, faction)
+	s.Races = countBreakdown(s.Races, race)
+	s.Roles = countBreakdown(s.Roles, role)
+	s.Genders = countBreakdown(s.Genders, gender)
+	distrib := s.FindDistribution(class)
+	if distrib != nil {
+		distrib.Races = countBreakdown(distrib.Races, race)
+	}
+}
+
+// FindBreakdown return the breakdown of the stats by provided field, one of Breakdowns
+func (s *Stats) FindBreakdown(name string) []*Breakdown {
+	switch name {
+	case "faction":
+		return s.Factions
+	case "race":
+		return s.Races
+	case "role":
+		return s.Roles
+	case "gender":
+		return s.Genders
+	}
+	return nil
+}
+
+// FindGuild return the stats of a guild by name and realm
+func (s *Stats) FindGuild(guild string, realm string) *GuildStats {
+	for _, v := range s.Guilds {
+		if v.Guild == guild && v.Realm == realm {
+			return v
+		}
+	}
+	return nil
+}
+
+func count(distributions []*Distribution, class string, spec string) ([]*Distribution, *Spec) {
+	var distrib *Distribution
+	for _, v := range distributions {
+		if v.Class == class {
+			distrib = v
+		}
+	}
+	if distrib == nil {
+		distrib = &Distribution{
+			Class: class,
+		}
+		distributions = append(distributions, distrib)
+	}
+	distrib.Total++
+	found := distrib.FindSpec(spec)
+	if found == nil {
+		found = &Spec{
+			Spec: spec,
+		}
+		distrib.Specs = append(distrib.Specs, found)
+	}
+	found.Count++
+	return distributions, found
+}
+
+func countBreakdown(breakdowns []*Breakdown, value string) []*Breakdown {
+	if value == "" {
+		return breakdowns
+	}
+	for _, v := range breakdowns {
+		if v.Value == value {
+			v.Count++
+			return breakdowns
+		}
+	}
+	return append(breakdowns, &Breakdown{Value: value, Count: 1})
+}
@@ -8,5 +8,8 @@ import (
 
 func init() {
 	beego.Router("/", &controllers.DefaultController{})
-	beego.Router("/stats/:dbname:string", &controllers.StatsController{}, "get:GetStats")
+	beego.Router("/stats/:dbname", &controllers.StatsController{}, "get:GetStats;post:PostStats")
+	beego.Router("/stats/:dbname/snapshots", &controllers.StatsController{}, "get:GetSnapshots")
+	beego.Router("/stats/:dbname/history", &controllers.StatsController{}, "get:GetHistory")
+	beego.Router("/stats/:dbname/diff", &controllers.StatsController{}, "get:GetDiff")
 }
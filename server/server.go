@@ -1,7 +1,7 @@
 package server
 
-import "github.com/dgraph-io/badger/v2"
+import "wowstatistician/helpers/databases"
 
 type Context struct {
-	Db *badger.DB
+	Store databases.Store
 }
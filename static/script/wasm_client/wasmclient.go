@@ -31,6 +31,22 @@ var classColors = map[string]string{
 	"Demon_Hunter": "rgba(178, 107, 178, 1)",
 }
 
+var factionColors = map[string]string{
+	"HORDE":    "rgba(178, 34, 34, 1)",
+	"ALLIANCE": "rgba(30, 80, 178, 1)",
+}
+
+var breakdownColors = []string{
+	"rgba(229, 130, 80, 1)",
+	"rgba(81, 132, 204, 1)",
+	"rgba(154, 178, 107, 1)",
+	"rgba(142, 122, 204, 1)",
+	"rgba(255, 204, 102, 1)",
+	"rgba(71, 178, 169, 1)",
+	"rgba(216, 119, 159, 1)",
+	"rgba(206, 214, 229, 1)",
+}
+
 func getStats(source string) models.Stats {
 	resp, err := http.Get("/stats/" + source)
 	if err != nil {
@@ -42,6 +58,17 @@ func getStats(source string) models.Stats {
 	return stats
 }
 
+func getHistory(source string) models.History {
+	resp, err := http.Get("/stats/" + source + "/history")
+	if err != nil {
+		log.Println(err)
+	}
+	defer resp.Body.Close()
+	history := models.History{}
+	json.NewDecoder(resp.Body).Decode(&history)
+	return history
+}
+
 func genLabelsExpanded(stats models.Stats) []string {
 	labels := []string{}
 	for _, distribution := range stats.Distributions {
@@ -65,7 +92,7 @@ func genDataExpanded(stats models.Stats) []interface{} {
 	data := []interface{}{}
 	for _, distribution := range stats.Distributions {
 		for _, spec := range distribution.Specs {
-			data = append(data, spec.Count)
+			data = append(data, spec.Share*100)
 		}
 	}
 	return data
@@ -74,7 +101,43 @@ func genDataExpanded(stats models.Stats) []interface{} {
 func genDataMerged(stats models.Stats) []interface{} {
 	data := []interface{}{}
 	for _, distribution := range stats.Distributions {
-		data = append(data, distribution.Total)
+		data = append(data, distribution.Share*100)
+	}
+	return data
+}
+
+// genErrorBars return the confidence interval of the share of each label as a floating bar, in percent
+func genErrorBars(stats models.Stats, labels []string, merged bool) []interface{} {
+	intervals := map[string][]float64{}
+	for _, distribution := range stats.Distributions {
+		if merged {
+			intervals[distribution.Class] = []float64{distribution.ShareLow * 100, distribution.ShareHigh * 100}
+			continue
+		}
+		for _, spec := range distribution.Specs {
+			label := fmt.Sprintf("%v - %v", spec.Spec, distribution.Class)
+			intervals[label] = []float64{spec.ShareLow * 100, spec.ShareHigh * 100}
+		}
+	}
+	errorBars := []interface{}{}
+	for _, label := range labels {
+		errorBars = append(errorBars, intervals[label])
+	}
+	return errorBars
+}
+
+func genLabelsBreakdown(stats models.Stats, breakdown string) []string {
+	labels := []string{}
+	for _, v := range stats.FindBreakdown(breakdown) {
+		labels = append(labels, strings.Title(strings.ToLower(v.Value)))
+	}
+	return labels
+}
+
+func genDataBreakdown(stats models.Stats, breakdown string) []interface{} {
+	data := []interface{}{}
+	for _, v := range stats.FindBreakdown(breakdown) {
+		data = append(data, v.Count)
 	}
 	return data
 }
@@ -127,10 +190,22 @@ func genColorsMerged(labels []string) []string {
 	return colors
 }
 
-func makeConfig(stats models.Stats, merged bool) *chartjs.Config {
+func genColorsBreakdown(labels []string) []string {
+	colors := []string{}
+	for i, v := range labels {
+		color, ok := factionColors[strings.ToUpper(v)]
+		if !ok {
+			color = breakdownColors[i%len(breakdownColors)]
+		}
+		colors = append(colors, color)
+	}
+	return colors
+}
+
+func makeConfig(stats models.Stats, breakdown string) *chartjs.Config {
 	config := &chartjs.Config{
 		Type: "bar",
-		Data: makeData(stats, merged),
+		Data: makeData(stats, breakdown),
 		Options: &chartjs.Options{
 			Scales: &chartjs.Scales{
 				YAxes: []*chartjs.Axe{
@@ -171,67 +246,314 @@ func makeConfig(stats models.Stats, merged bool) *chartjs.Config {
 	return config
 }
 
-func makeData(stats models.Stats, merged bool) *chartjs.Data {
+func fadeColors(colors []string) []string {
+	faded := []string{}
+	for _, color := range colors {
+		faded = append(faded, strings.Replace(color, ", 1)", ", 0.3)", 1))
+	}
+	return faded
+}
+
+// makeData chart class and spec shares in percent next to their 95% confidence interval, and other breakdowns as player counts
+func makeData(stats models.Stats, breakdown string) *chartjs.Data {
 	labels := []string{}
 	data := []interface{}{}
 	colors := []string{}
-	if merged {
+	switch breakdown {
+	case "class":
 		labels = genLabelsMerged(stats)
 		data = genDataMerged(stats)
 		labels, data = minToMax(labels, data)
 		colors = genColorsMerged(labels)
-	} else {
+	case "spec":
 		labels = genLabelsExpanded(stats)
 		data = genDataExpanded(stats)
 		labels, data = minToMax(labels, data)
 		colors = genColorsExpanded(labels)
+	default:
+		labels = genLabelsBreakdown(stats, breakdown)
+		data = genDataBreakdown(stats, breakdown)
+		labels, data = minToMax(labels, data)
+		colors = genColorsBreakdown(labels)
+		return &chartjs.Data{
+			Labels: labels,
+			Datasets: []*chartjs.Dataset{
+				{
+					Label:           "Number of players",
+					Data:            data,
+					BackgroundColor: colors,
+				},
+			},
+		}
 	}
 	return &chartjs.Data{
 		Labels: labels,
 		Datasets: []*chartjs.Dataset{
 			{
-				Label:           "Number of players",
+				Label:           "Share of players - %",
 				Data:            data,
 				BackgroundColor: colors,
 			},
+			{
+				Label:           "95% confidence interval - %",
+				Data:            genErrorBars(stats, labels, breakdown == "class"),
+				BackgroundColor: fadeColors(colors),
+			},
+		},
+	}
+}
+
+func makeLineConfig(history models.History, merged bool) *chartjs.Config {
+	config := &chartjs.Config{
+		Type: "line",
+		Data: makeLineData(history, merged),
+		Options: &chartjs.Options{
+			Scales: &chartjs.Scales{
+				YAxes: []*chartjs.Axe{
+					{
+						Type: "linear",
+						ScaleLabel: &chartjs.ScaleLabel{
+							Display: utils.Bool(false),
+						},
+						Ticks: &chartjs.Ticks{
+							BeginAtZero: utils.Bool(true),
+						},
+					},
+				},
+				XAxes: []*chartjs.Axe{
+					{
+						Type: "category",
+					},
+				},
+			},
+			Responsive:          utils.Bool(true),
+			MaintainAspectRatio: utils.Bool(false),
+			Title: &chartjs.Title{
+				Display: utils.Bool(true),
+				Text:    fmt.Sprintf("Share of players over time for: %v - %%", strings.Title(history.Source)),
+			},
+		},
+	}
+	return config
+}
+
+func makeLineData(history models.History, merged bool) *chartjs.Data {
+	labels := []string{}
+	series := map[string][]interface{}{}
+	names := []string{}
+	for i, point := range history.Points {
+		labels = append(labels, point.SyncDate)
+		shares := point.Specs
+		if merged {
+			shares = point.Classes
+		}
+		for _, share := range shares {
+			name := share.Class
+			if !merged {
+				name = fmt.Sprintf("%v - %v", share.Spec, share.Class)
+			}
+			if _, ok := series[name]; !ok {
+				names = append(names, name)
+				series[name] = make([]interface{}, len(history.Points))
+			}
+			series[name][i] = share.Share * 100
+		}
+	}
+	datasets := []*chartjs.Dataset{}
+	for _, name := range names {
+		colors := []string{}
+		for range series[name] {
+			colors = append(colors, genColorsExpanded([]string{name})[0])
+		}
+		datasets = append(datasets, &chartjs.Dataset{
+			Label:           name,
+			Data:            series[name],
+			BackgroundColor: colors,
+		})
+	}
+	return &chartjs.Data{
+		Labels:   labels,
+		Datasets: datasets,
+	}
+}
+
+// makeBoxConfig draw the equipped item levels of every spec as box plots of every source compared
+// Each source is drawn as floating bars: a faded whisker from min to max, a box from p25 to p75 and a thin mark at the median
+func makeBoxConfig(sources []string, stats []models.Stats) *chartjs.Config {
+	titles := []string{}
+	for _, source := range sources {
+		titles = append(titles, strings.Title(source))
+	}
+	config := &chartjs.Config{
+		Type: "bar",
+		Data: makeBoxData(sources, stats),
+		Options: &chartjs.Options{
+			Scales: &chartjs.Scales{
+				YAxes: []*chartjs.Axe{
+					{
+						Type: "linear",
+						ScaleLabel: &chartjs.ScaleLabel{
+							Display: utils.Bool(false),
+						},
+						Ticks: &chartjs.Ticks{
+							BeginAtZero: utils.Bool(false),
+						},
+					},
+				},
+				XAxes: []*chartjs.Axe{
+					{
+						Type: "category",
+					},
+				},
+			},
+			Responsive:          utils.Bool(true),
+			MaintainAspectRatio: utils.Bool(false),
+			Title: &chartjs.Title{
+				Display: utils.Bool(true),
+				Text:    fmt.Sprintf("Equipped item level per spec for: %v - min to max, p25 to p75 and median", strings.Join(titles, ", ")),
+			},
 		},
 	}
+	return config
+}
+
+// boxMarkWidth is the item level range drawn around the median so it shows as a line
+const boxMarkWidth = 0.5
+
+// makeBoxData chart the item levels of the specs of every source, specs being sorted by the median of the first source listing them
+func makeBoxData(sources []string, stats []models.Stats) *chartjs.Data {
+	itemLevels := []map[string]*models.ItemLevels{}
+	medians := map[string]int{}
+	labels := []string{}
+	for _, sourceStats := range stats {
+		specs := map[string]*models.ItemLevels{}
+		for _, distribution := range sourceStats.Distributions {
+			for _, spec := range distribution.Specs {
+				if spec.ItemLevel == nil {
+					continue
+				}
+				label := fmt.Sprintf("%v - %v", spec.Spec, distribution.Class)
+				specs[label] = spec.ItemLevel
+				if _, ok := medians[label]; !ok {
+					medians[label] = spec.ItemLevel.Median
+					labels = append(labels, label)
+				}
+			}
+		}
+		itemLevels = append(itemLevels, specs)
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		return medians[labels[i]] < medians[labels[j]]
+	})
+	// A single source is drawn with class colors, several with a color per source
+	colors := genColorsExpanded(labels)
+	datasets := []*chartjs.Dataset{}
+	for i, source := range sources {
+		if len(sources) > 1 {
+			colors = []string{}
+			for range labels {
+				colors = append(colors, breakdownColors[i%len(breakdownColors)])
+			}
+		}
+		whiskers := []interface{}{}
+		boxes := []interface{}{}
+		marks := []interface{}{}
+		markColors := []string{}
+		for _, label := range labels {
+			itemLevel := itemLevels[i][label]
+			if itemLevel == nil {
+				whiskers = append(whiskers, nil)
+				boxes = append(boxes, nil)
+				marks = append(marks, nil)
+			} else {
+				whiskers = append(whiskers, []int{itemLevel.Min, itemLevel.Max})
+				boxes = append(boxes, []int{itemLevel.P25, itemLevel.P75})
+				marks = append(marks, []float64{float64(itemLevel.Median) - boxMarkWidth, float64(itemLevel.Median) + boxMarkWidth})
+			}
+			markColors = append(markColors, "rgba(54, 54, 54, 1)")
+		}
+		title := strings.Title(source)
+		datasets = append(datasets,
+			&chartjs.Dataset{
+				Label:           title + " min - max",
+				Data:            whiskers,
+				BackgroundColor: fadeColors(colors),
+			},
+			&chartjs.Dataset{
+				Label:           title + " p25 - p75",
+				Data:            boxes,
+				BackgroundColor: colors,
+			},
+			&chartjs.Dataset{
+				Label:           title + " median",
+				Data:            marks,
+				BackgroundColor: markColors,
+			},
+		)
+	}
+	return &chartjs.Data{
+		Labels:   labels,
+		Datasets: datasets,
+	}
 }
 
-func makeChart(source string, merged bool) {
+// makeChart draw the stats of a source broken down by class, spec or any of models.Breakdowns
+// Shares over time are only tracked by class and spec, other breakdowns are always drawn from the latest stats
+// The item level chart always compare specs, whatever the breakdown, of the source and the sources checked to compare it with
+func makeChart(source string, breakdown string, chartType string) {
 	chart := chartjs.GetChart("statsChart")
 	ctx := dom.Document().GetElementById("stats").GetContext("2d")
-	if chart.Value.IsUndefined() {
-		stats := getStats(source)
-		config := makeConfig(stats, merged)
-		chart = chartjs.NewChart(ctx, config)
-		chart.Register("statsChart")
-	} else {
+	if !chart.Value.IsUndefined() {
 		chart.Detroy()
-		stats := getStats(source)
-		config := makeConfig(stats, merged)
-		chart = chartjs.NewChart(ctx, config)
-		chart.Register("statsChart")
 	}
+	var config *chartjs.Config
+	if chartType == "itemlevel" {
+		sources := getComparedSources(source)
+		stats := []models.Stats{}
+		for _, compared := range sources {
+			stats = append(stats, getStats(compared))
+		}
+		config = makeBoxConfig(sources, stats)
+	} else if chartType == "line" && (breakdown == "class" || breakdown == "spec") {
+		config = makeLineConfig(getHistory(source), breakdown == "class")
+	} else {
+		config = makeConfig(getStats(source), breakdown)
+	}
+	chart = chartjs.NewChart(ctx, config)
+	chart.Register("statsChart")
 }
 
-func isMerged() bool {
-	var text string
+func getBreakdown() string {
 	radios := dom.Document().GetElementsByName("merge")
 	for i := 0; i < len(radios); i++ {
 		if radios[i].Get("checked").Bool() {
-			text = radios[i].Get("value").String()
+			return radios[i].Get("value").String()
+		}
+	}
+	return "class"
+}
 
+// getComparedSources return the selected source followed by the other sources checked to compare it with
+func getComparedSources(source string) []string {
+	sources := []string{source}
+	checkboxes := dom.Document().GetElementsByName("compare")
+	for i := 0; i < len(checkboxes); i++ {
+		value := checkboxes[i].Get("value").String()
+		if checkboxes[i].Get("checked").Bool() && value != source {
+			sources = append(sources, value)
 		}
 	}
-	switch text {
-	case "spec":
-		return false
-	case "class":
-		return true
-	default:
-		return false
+	return sources
+}
+
+func getChartType() string {
+	radios := dom.Document().GetElementsByName("chart")
+	for i := 0; i < len(radios); i++ {
+		if radios[i].Get("checked").Bool() {
+			return radios[i].Get("value").String()
+		}
 	}
+	return "bar"
 }
 
 func setDate(source string) {
@@ -244,8 +566,7 @@ func dropDownCallback(this js.Value, args []js.Value) interface{} {
 		dropDown := dom.Document().GetElementById("source")
 		selected := dropDown.Get("selectedIndex").Int()
 		source := dropDown.Get("options").Index(selected).Get("text").String()
-		merged := isMerged()
-		makeChart(strings.ToLower(source), merged)
+		makeChart(strings.ToLower(source), getBreakdown(), getChartType())
 		setDate(strings.ToLower(source))
 	}()
 	return nil
@@ -258,5 +579,13 @@ func main() {
 	for i := 0; i < len(radios); i++ {
 		radios[i].AddEventListener("change", dropDownCallback)
 	}
+	charts := dom.Document().GetElementsByName("chart")
+	for i := 0; i < len(charts); i++ {
+		charts[i].AddEventListener("change", dropDownCallback)
+	}
+	compared := dom.Document().GetElementsByName("compare")
+	for i := 0; i < len(compared); i++ {
+		compared[i].AddEventListener("change", dropDownCallback)
+	}
 	select {}
 }
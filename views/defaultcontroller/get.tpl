@@ -49,8 +49,9 @@
 											</div>
 										</div>
 										<p class="help">
-											Show class and spec distribution
-											based on Blizzard's leatherboard API
+											Show class, spec, faction, race,
+											role and gender distribution based
+											on Blizzard's leatherboard API
 										</p>
 									</div>
 									<div class="field">
@@ -72,8 +73,109 @@
 												/>
 												Class
 											</label>
+											<label class="radio">
+												<input
+													type="radio"
+													name="merge"
+													value="faction"
+												/>
+												Faction
+											</label>
+											<label class="radio">
+												<input
+													type="radio"
+													name="merge"
+													value="race"
+												/>
+												Race
+											</label>
+											<label class="radio">
+												<input
+													type="radio"
+													name="merge"
+													value="role"
+												/>
+												Role
+											</label>
+											<label class="radio">
+												<input
+													type="radio"
+													name="merge"
+													value="gender"
+												/>
+												Gender
+											</label>
+										</div>
+									</div>
+									<div class="field">
+										<div class="control is-expanded">
+											<label class="radio">
+												<input
+													type="radio"
+													name="chart"
+													value="bar"
+													checked
+												/>
+												Latest
+											</label>
+											<label class="radio">
+												<input
+													type="radio"
+													name="chart"
+													value="line"
+												/>
+												Over time
+											</label>
+											<label class="radio">
+												<input
+													type="radio"
+													name="chart"
+													value="itemlevel"
+												/>
+												Item level
+											</label>
 										</div>
 									</div>
+									<div class="field">
+										<div class="control is-expanded">
+											<label class="checkbox">
+												<input
+													type="checkbox"
+													name="compare"
+													value="mythic"
+												/>
+												Mythic
+											</label>
+											<label class="checkbox">
+												<input
+													type="checkbox"
+													name="compare"
+													value="raid"
+												/>
+												Raid
+											</label>
+											<label class="checkbox">
+												<input
+													type="checkbox"
+													name="compare"
+													value="arena"
+												/>
+												Arena
+											</label>
+											<label class="checkbox">
+												<input
+													type="checkbox"
+													name="compare"
+													value="rbg"
+												/>
+												Rbg
+											</label>
+										</div>
+										<p class="help">
+											Sources to compare the item levels
+											of the selected one with
+										</p>
+									</div>
 								</div>
 							</div>
 							<div><canvas id="stats" height="400"></canvas></div>
@@ -2,10 +2,14 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 	"wowstatistician/cmd"
 	"wowstatistician/controllers"
+	"wowstatistician/helpers"
 	"wowstatistician/helpers/databases"
 	_ "wowstatistician/routers"
 
@@ -14,6 +18,21 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+// storeFlag select the store every command read from and write to
+var storeFlag = &cli.StringFlag{
+	Name:    "store",
+	Value:   databases.DefaultStore,
+	Usage:   "Store to use, badger:<directory> for a single badger db or sqlite:<file> for a single sqlite file",
+	EnvVars: []string{"WOWSTATISTICIAN_STORE"},
+}
+
+// publishTokenFlag hold the token freshly computed stats are published to a running serve command with
+var publishTokenFlag = &cli.StringFlag{
+	Name:    "publish-token",
+	Usage:   "Token stats are published with, serve accepting no published stats without one",
+	EnvVars: []string{"WOWSTATISTICIAN_PUBLISH_TOKEN"},
+}
+
 func main() {
 	app := &cli.App{
 		Name:  "Wow Statistician",
@@ -28,19 +47,67 @@ func main() {
 						Name:    "generate",
 						Aliases: []string{"g"},
 						Usage:   "Generate stats for a db",
-						Flags: []cli.Flag{
+						Flags: append([]cli.Flag{
+							storeFlag,
 							&cli.StringFlag{
 								Name:     "database",
 								Aliases:  []string{"db"},
 								Required: true,
+								Usage:    "Db to generate stats for, ie: raid, or " + databases.EndgameSource + " to combine every source counting each character once",
 							},
-						},
+							&cli.StringFlag{
+								Name:    "bracket",
+								Aliases: []string{"b"},
+								Usage:   "Pvp bracket to restrict stats to, ie: 3v3",
+							},
+							&cli.IntFlag{
+								Name:  "min-rating",
+								Usage: "Minimum pvp rating to restrict stats to, ie: 2400",
+							},
+							&cli.IntFlag{
+								Name:  "top",
+								Usage: "Pvp ladder rank to restrict stats to, ie: 500",
+							},
+							&cli.IntFlag{
+								Name:  "top-guilds",
+								Usage: "Raid guilds, ranked by kill time, to restrict stats to, ie: 100",
+							},
+							&cli.IntFlag{
+								Name:  "max-roster-rank",
+								Value: -1,
+								Usage: "Highest guild roster rank raid members are restricted to, ie: 3 for ranks 0 to 3",
+							},
+							&cli.BoolFlag{
+								Name:  "latest-crawl",
+								Usage: "Restrict stats to characters listed by the latest complete crawl of their leatherboard",
+							},
+							&cli.StringFlag{
+								Name:    "region",
+								Aliases: []string{"rg"},
+								Usage:   "Region to restrict stats to, ie: eu - default to every region combined",
+							},
+							&cli.StringFlag{
+								Name:  "publish",
+								Usage: "Url of a running serve command to publish the generated stats to, ie: http://localhost:8080",
+							},
+							publishTokenFlag,
+						}, queryFlags...),
 						Action: func(c *cli.Context) error {
+							filter, err := filterFromFlags(c)
+							if err != nil {
+								return err
+							}
 							log.Println("[+] Generating stats for db: databases/" + c.String("database"))
-							err := databases.WriteStatsForDb(c.String("database"))
+							stats, err := databases.WriteStatsForDb(c.String("store"), c.String("database"), filter)
 							if err != nil {
 								return err
 							}
+							if c.String("publish") != "" {
+								err = cmd.PublishStats(c.String("publish"), c.String("publish-token"), filter.Name(c.String("database")), *stats)
+								if err != nil {
+									return err
+								}
+							}
 							log.Println("[-] Generating stats for db: databases/" + c.String("database"))
 							return nil
 						},
@@ -49,16 +116,19 @@ func main() {
 						Name:    "print",
 						Aliases: []string{"p"},
 						Usage:   "Print stats maps object on console",
-						Flags: []cli.Flag{
-							&cli.StringFlag{
-								Name:     "database",
-								Aliases:  []string{"db"},
-								Required: true,
+						Flags: append(statsFlags(),
+							&cli.Int64Flag{
+								Name:  "snapshot",
+								Usage: "Stats snapshot to print, as listed by compute snapshots - default to the latest",
 							},
-						},
+						),
 						Action: func(c *cli.Context) error {
+							filter, err := filterFromFlags(c)
+							if err != nil {
+								return err
+							}
 							log.Println("[+] Printing stats for db: databases/" + c.String("database"))
-							stats, err := databases.ReadStatsDb(c.String("database"))
+							stats, err := databases.ReadStatsDb(c.String("store"), filter.Name(c.String("database")), c.Int64("snapshot"))
 							if err != nil {
 								return err
 							}
@@ -67,6 +137,71 @@ func main() {
 							return nil
 						},
 					},
+					{
+						Name:    "snapshots",
+						Aliases: []string{"s"},
+						Usage:   "List the stats snapshots of a db",
+						Flags:   statsFlags(),
+						Action: func(c *cli.Context) error {
+							filter, err := filterFromFlags(c)
+							if err != nil {
+								return err
+							}
+							snapshots, err := databases.ListSnapshotsDb(c.String("store"), filter.Name(c.String("database")))
+							if err != nil {
+								return err
+							}
+							for _, snapshot := range snapshots {
+								fmt.Printf("%v\t%v\n", snapshot, time.Unix(snapshot, 0).Format(time.RFC3339))
+							}
+							return nil
+						},
+					},
+					{
+						Name:    "diff",
+						Aliases: []string{"d"},
+						Usage:   "Show how class and spec shares changed between two stats snapshots of a db",
+						Flags: append(statsFlags(),
+							&cli.StringFlag{
+								Name:  "from",
+								Usage: "Diff from the earliest snapshot taken at or after this unix timestamp or date, ie: 2020-06-01 - default to the snapshot preceding to",
+							},
+							&cli.StringFlag{
+								Name:  "to",
+								Usage: "Diff to the latest snapshot taken at or before this unix timestamp or date, ie: 2020-06-15 - default to the latest",
+							},
+							&cli.IntFlag{
+								Name:  "movers",
+								Value: 5,
+								Usage: "Number of specs whose share moved the most to highlight",
+							},
+							&cli.StringFlag{
+								Name:    "format",
+								Aliases: []string{"f"},
+								Value:   "table",
+								Usage:   "Output format, one of: " + strings.Join(helpers.DiffFormats, ", "),
+							},
+						),
+						Action: func(c *cli.Context) error {
+							filter, err := filterFromFlags(c)
+							if err != nil {
+								return err
+							}
+							from, err := parseSnapshotFlag(c, "from", databases.ParseSnapshot)
+							if err != nil {
+								return err
+							}
+							to, err := parseSnapshotFlag(c, "to", databases.ParseSnapshotUntil)
+							if err != nil {
+								return err
+							}
+							diff, err := databases.DiffDb(c.String("store"), filter.Name(c.String("database")), from, to, c.Int("movers"))
+							if err != nil {
+								return err
+							}
+							return helpers.WriteDiff(os.Stdout, diff, c.String("format"))
+						},
+					},
 				},
 			},
 			{
@@ -79,16 +214,31 @@ func main() {
 						Aliases: []string{"a"},
 						Usage:   "Get and store arena leatherboards",
 						Flags: []cli.Flag{
+							storeFlag,
 							&cli.StringFlag{
 								Name:    "region",
 								Aliases: []string{"rg"},
 								Value:   "eu",
 								Usage:   "Region to query arena leatherboards from",
 							},
+							&cli.StringSliceFlag{
+								Name:    "bracket",
+								Aliases: []string{"b"},
+								Usage:   "Brackets to query, by name or family, ie: 3v3 or shuffle - default to every bracket",
+							},
+							&cli.BoolFlag{
+								Name:  "snapshot-profiles",
+								Usage: "Also keep a dated copy of every retreived profile, to follow profiles over time",
+							},
 						},
 						Action: func(c *cli.Context) error {
 							log.Println("[+] Saving arena profiles")
-							err := cmd.SaveArenaProfiles(c.String("region"))
+							store, err := openRetreiveStore(c, "arena")
+							if err != nil {
+								return err
+							}
+							defer store.Close()
+							err = cmd.SaveArenaProfiles(store, c.String("region"), c.StringSlice("bracket"))
 							if err != nil {
 								return err
 							}
@@ -101,16 +251,26 @@ func main() {
 						Aliases: []string{"m"},
 						Usage:   "Get and store mythic+ leatherboards",
 						Flags: []cli.Flag{
+							storeFlag,
 							&cli.StringFlag{
 								Name:    "region",
 								Aliases: []string{"rg"},
 								Value:   "eu",
 								Usage:   "Region to query mythic+ leatherboards from",
 							},
+							&cli.BoolFlag{
+								Name:  "snapshot-profiles",
+								Usage: "Also keep a dated copy of every retreived profile, to follow profiles over time",
+							},
 						},
 						Action: func(c *cli.Context) error {
 							log.Println("[+] Saving mythic+ profiles")
-							err := cmd.SaveMythicProfiles(c.String("region"))
+							store, err := openRetreiveStore(c, "mythic")
+							if err != nil {
+								return err
+							}
+							defer store.Close()
+							err = cmd.SaveMythicProfiles(store, c.String("region"))
 							if err != nil {
 								return err
 							}
@@ -123,6 +283,7 @@ func main() {
 						Aliases: []string{"r"},
 						Usage:   "Get and store raid leatherboard",
 						Flags: []cli.Flag{
+							storeFlag,
 							&cli.StringFlag{
 								Name:    "region",
 								Aliases: []string{"rg"},
@@ -135,10 +296,19 @@ func main() {
 								Value:   "nyalotha-the-waking-city",
 								Usage:   "Raid to query leatherboard from",
 							},
+							&cli.BoolFlag{
+								Name:  "snapshot-profiles",
+								Usage: "Also keep a dated copy of every retreived profile, to follow profiles over time",
+							},
 						},
 						Action: func(c *cli.Context) error {
 							log.Println("[+] Saving raid profiles")
-							err := cmd.SaveRaidProfiles(c.String("region"), c.String("raid"))
+							store, err := openRetreiveStore(c, "raid")
+							if err != nil {
+								return err
+							}
+							defer store.Close()
+							err = cmd.SaveRaidProfiles(store, c.String("region"), c.String("raid"))
 							if err != nil {
 								return err
 							}
@@ -151,16 +321,26 @@ func main() {
 						Aliases: []string{"rb"},
 						Usage:   "Get and store rbg leatherboard",
 						Flags: []cli.Flag{
+							storeFlag,
 							&cli.StringFlag{
 								Name:    "region",
 								Aliases: []string{"rg"},
 								Value:   "eu",
 								Usage:   "Region to query rbg leatherboards from",
 							},
+							&cli.BoolFlag{
+								Name:  "snapshot-profiles",
+								Usage: "Also keep a dated copy of every retreived profile, to follow profiles over time",
+							},
 						},
 						Action: func(c *cli.Context) error {
 							log.Println("[+] Saving rbg profiles")
-							err := cmd.SaveRbgProfiles(c.String("region"))
+							store, err := openRetreiveStore(c, "rbg")
+							if err != nil {
+								return err
+							}
+							defer store.Close()
+							err = cmd.SaveRbgProfiles(store, c.String("region"))
 							if err != nil {
 								return err
 							}
@@ -168,19 +348,372 @@ func main() {
 							return nil
 						},
 					},
+					{
+						Name:    "refresh",
+						Aliases: []string{"f"},
+						Usage:   "Fetch again the stored profiles of a db last fetched too long ago",
+						Flags: []cli.Flag{
+							storeFlag,
+							&cli.StringFlag{
+								Name:     "database",
+								Aliases:  []string{"db"},
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:    "region",
+								Aliases: []string{"rg"},
+								Value:   "eu",
+								Usage:   "Region to refresh profiles of",
+							},
+							&cli.StringFlag{
+								Name:  "older-than",
+								Value: "7d",
+								Usage: "Age profiles are refreshed past, as days or a duration, ie: 7d or 36h",
+							},
+							&cli.BoolFlag{
+								Name:  "snapshot-profiles",
+								Usage: "Also keep a dated copy of every refreshed profile, to follow profiles over time",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							olderThan, err := databases.ParseAge(c.String("older-than"))
+							if err != nil {
+								return err
+							}
+							log.Println("[+] Refreshing profiles for db: " + c.String("database"))
+							store, err := openRetreiveStore(c, c.String("database"))
+							if err != nil {
+								return err
+							}
+							defer store.Close()
+							err = cmd.RefreshProfiles(store, c.String("region"), olderThan)
+							if err != nil {
+								return err
+							}
+							log.Println("[-] Refreshing profiles for db: " + c.String("database"))
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:    "export",
+				Aliases: []string{"e"},
+				Usage:   "Export the profiles, runs or stats of a db as rows, for use with pandas or DuckDB",
+				Flags: []cli.Flag{
+					storeFlag,
+					&cli.StringFlag{
+						Name:     "database",
+						Aliases:  []string{"db"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "region",
+						Aliases: []string{"rg"},
+						Usage:   "Region to restrict the export to, ie: eu - default to every region",
+					},
+					&cli.StringFlag{
+						Name:  "what",
+						Value: "profiles",
+						Usage: "Rows to export, one of: " + strings.Join(databases.ExportKinds, ", "),
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Value:   "csv",
+						Usage:   "Output format, one of: " + strings.Join(helpers.ExportFormats, ", "),
+					},
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Value:   "-",
+						Usage:   "File to export to, - for the standard output",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					out := os.Stdout
+					if c.String("out") != "-" {
+						file, err := os.Create(c.String("out"))
+						if err != nil {
+							return errors.New("main: could not export - " + err.Error())
+						}
+						defer file.Close()
+						out = file
+					}
+					rows, err := databases.ExportDb(c.String("store"), c.String("database"), c.String("region"), c.String("what"), out, c.String("format"))
+					if err != nil {
+						return err
+					}
+					log.Printf("[-] Exported %v %v rows of db: %v\n", rows, c.String("what"), c.String("database"))
+					return nil
+				},
+			},
+			{
+				Name:    "query",
+				Aliases: []string{"q"},
+				Usage:   "List the profiles of a db matching a class, spec, realm or faction, looked up by the store indexes",
+				Flags: append([]cli.Flag{
+					storeFlag,
+					&cli.StringFlag{
+						Name:     "database",
+						Aliases:  []string{"db"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "region",
+						Aliases: []string{"rg"},
+						Usage:   "Region to restrict profiles to, ie: eu - default to every region",
+					},
+					&cli.StringFlag{
+						Name:    "bracket",
+						Aliases: []string{"b"},
+						Usage:   "Pvp bracket to restrict profiles to, ie: 3v3",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Value:   "csv",
+						Usage:   "Output format, one of: " + strings.Join(helpers.ExportFormats, ", "),
+					},
+				}, queryFlags...),
+				Action: func(c *cli.Context) error {
+					filter, err := filterFromFlags(c)
+					if err != nil {
+						return err
+					}
+					rows, err := databases.QueryDb(c.String("store"), c.String("database"), filter, os.Stdout, c.String("format"))
+					if err != nil {
+						return err
+					}
+					log.Printf("[-] Found %v profiles in db: %v\n", rows, c.String("database"))
+					return nil
+				},
+			},
+			{
+				Name:      "import",
+				Aliases:   []string{"i"},
+				Usage:     "Import profile rows, as exported by the export command, to a db",
+				ArgsUsage: "file",
+				Flags: []cli.Flag{
+					storeFlag,
+					&cli.StringFlag{
+						Name:     "database",
+						Aliases:  []string{"db"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "region",
+						Aliases: []string{"rg"},
+						Usage:   "Region of the rows carrying none, ie: eu",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Value:   "jsonl",
+						Usage:   "Input format, one of: " + strings.Join(helpers.ImportFormats, ", "),
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return errors.New("main: could not import - expected a single file, - for the standard input")
+					}
+					in := os.Stdin
+					if c.Args().First() != "-" {
+						file, err := os.Open(c.Args().First())
+						if err != nil {
+							return errors.New("main: could not import - " + err.Error())
+						}
+						defer file.Close()
+						in = file
+					}
+					imported, skipped, err := databases.ImportDb(c.String("store"), c.String("database"), c.String("region"), in, c.String("format"))
+					if err != nil {
+						return err
+					}
+					log.Printf("[-] Imported %v rows to db: %v, %v invalid rows skipped\n", imported, c.String("database"), skipped)
+					return nil
+				},
+			},
+			{
+				Name:  "db",
+				Usage: "Maintain the store",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "backup",
+						Usage: "Back up a badger store, or a source of it, to a file along a manifest of its record count and checksum",
+						Flags: []cli.Flag{
+							storeFlag,
+							&cli.StringFlag{
+								Name:    "database",
+								Aliases: []string{"db"},
+								Usage:   "Source to restrict the backup to, ie: raid or stats - default to the whole store",
+							},
+							&cli.Uint64Flag{
+								Name:  "since",
+								Usage: "Version of a previous backup to back up changes since, as reported by its manifest - default to a full backup",
+							},
+							&cli.StringFlag{
+								Name:     "out",
+								Aliases:  []string{"o"},
+								Required: true,
+								Usage:    "File to back up to",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							manifest, err := databases.BackupDb(c.String("store"), c.String("database"), c.Uint64("since"), c.String("out"))
+							if err != nil {
+								return err
+							}
+							log.Printf("[-] Backed up %v records, %v bytes, up to version %v - sha256: %v\n", manifest.Records, manifest.Bytes, manifest.Version, manifest.SHA256)
+							return nil
+						},
+					},
+					{
+						Name:  "restore",
+						Usage: "Restore a backup file to a badger store, checking it against its manifest",
+						Flags: []cli.Flag{
+							storeFlag,
+							&cli.StringFlag{
+								Name:     "in",
+								Aliases:  []string{"i"},
+								Required: true,
+								Usage:    "Backup file to restore, incremental backups being restored after the backup they follow",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							manifest, err := databases.RestoreDb(c.String("store"), c.String("in"))
+							if err != nil {
+								return err
+							}
+							log.Printf("[-] Restored %v records, %v bytes - sha256: %v\n", manifest.Records, manifest.Bytes, manifest.SHA256)
+							return nil
+						},
+					},
+					{
+						Name:  "gc",
+						Usage: "Compact a badger store and garbage collect its value log",
+						Flags: []cli.Flag{
+							storeFlag,
+							&cli.Float64Flag{
+								Name:  "discard-ratio",
+								Value: 0.5,
+								Usage: "Stale share a value log file must reach to be rewritten",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							before, after, err := databases.GcDb(c.String("store"), c.Float64("discard-ratio"))
+							if err != nil {
+								return err
+							}
+							log.Printf("[-] Garbage collected db - lsm: %v to %v bytes, value log: %v to %v bytes\n", before.LSMSize, after.LSMSize, before.VlogSize, after.VlogSize)
+							return nil
+						},
+					},
+					{
+						Name:  "info",
+						Usage: "Show the size, last write and key counts by kind and source of a badger store",
+						Flags: []cli.Flag{
+							storeFlag,
+						},
+						Action: func(c *cli.Context) error {
+							info, err := databases.InfoDb(c.String("store"))
+							if err != nil {
+								return err
+							}
+							fmt.Printf("lsm size\t%v\nvalue log size\t%v\nlast write\t%v\n\n", info.LSMSize, info.VlogSize, info.LastWrite.Format(time.RFC3339))
+							fmt.Printf("prefix\tkeys\testimated bytes\tversion\n")
+							for _, prefix := range info.Prefixes {
+								fmt.Printf("%v\t%v\t%v\t%v\n", prefix.Prefix, prefix.Keys, prefix.Bytes, prefix.Version)
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "verify",
+						Usage: "Decode every record of a badger store and report those that are corrupt",
+						Flags: []cli.Flag{
+							storeFlag,
+							&cli.BoolFlag{
+								Name:  "delete",
+								Usage: "Delete the corrupt records",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							checked, corrupt, err := databases.VerifyDb(c.String("store"), c.Bool("delete"))
+							if err != nil {
+								return err
+							}
+							for _, entry := range corrupt {
+								fmt.Printf("%v\t%v\n", entry.Key, entry.Err)
+							}
+							if c.Bool("delete") {
+								log.Printf("[-] Verified %v records, %v corrupt records deleted\n", checked, len(corrupt))
+								return nil
+							}
+							log.Printf("[-] Verified %v records, %v corrupt\n", checked, len(corrupt))
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "migrate",
+				Usage: "Rewrite stored profiles to the current record format",
+				Flags: []cli.Flag{
+					storeFlag,
+					&cli.StringSliceFlag{
+						Name:    "database",
+						Aliases: []string{"db"},
+						Value:   cli.NewStringSlice("raid", "mythic", "arena", "rbg", "stats"),
+						Usage:   "Databases to migrate, legacy per database badger dbs being imported in the single store",
+					},
+					&cli.StringFlag{
+						Name:    "region",
+						Aliases: []string{"rg"},
+						Value:   "eu",
+						Usage:   "Region the profiles of legacy badger dbs were retreived from",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					for _, dbname := range c.StringSlice("database") {
+						log.Println("[+] Migrating db: " + dbname)
+						scope := databases.Scope{Source: dbname, Region: c.String("region")}
+						if dbname == "stats" {
+							scope.Region = ""
+						}
+						migrated, err := databases.MigrateDb(c.String("store"), scope)
+						if err != nil {
+							return err
+						}
+						log.Printf("[-] Migrating db: %v - %v keys rewritten\n", dbname, migrated)
+					}
+					return nil
 				},
 			},
 			{
 				Name:    "serve",
 				Aliases: []string{"s"},
 				Usage:   "Serve results as html",
+				Flags: []cli.Flag{
+					storeFlag,
+					&cli.DurationFlag{
+						Name:  "reload",
+						Value: time.Minute,
+						Usage: "Interval stats are reloaded from the store at, the store being only held open while reloading",
+					},
+					publishTokenFlag,
+				},
 				Action: func(c *cli.Context) error {
-					db, err := databases.OpenDB("databases/stats")
-					controllers.Db = db
+					cache := databases.NewStatsCache(c.String("store"))
+					err := cache.Reload()
 					if err != nil {
-						return errors.New("main: could not open stats db - " + err.Error())
+						return errors.New("main: could not load stats - " + err.Error())
 					}
-					defer db.Close()
+					stop := make(chan struct{})
+					defer close(stop)
+					go cache.Watch(c.Duration("reload"), stop)
+					controllers.Cache = cache
+					controllers.PublishToken = c.String("publish-token")
 					beego.Run()
 					return nil
 				},
@@ -192,3 +725,114 @@ func main() {
 		log.Fatalln(err)
 	}
 }
+
+// queryFlags select profiles by their indexed fields
+var queryFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "class",
+		Usage: "Class to restrict profiles to, ie: Paladin",
+	},
+	&cli.StringFlag{
+		Name:  "spec",
+		Usage: "Spec to restrict profiles to, ie: Holy",
+	},
+	&cli.StringFlag{
+		Name:  "realm",
+		Usage: "Realm slug to restrict profiles to, ie: kazzak",
+	},
+	&cli.StringFlag{
+		Name:  "faction",
+		Usage: "Faction to restrict profiles to, ie: HORDE",
+	},
+	&cli.StringFlag{
+		Name:  "filter",
+		Usage: "Conditions profiles must all meet, on class, spec, realm, faction, race, gender, role, ilvl, avgilvl, level or rating, ie: \"faction=HORDE realm=kazzak ilvl>=470\", multi-word values being written with dashes, ie: spec=beast-mastery",
+	},
+}
+
+// statsFlags return the flags selecting stored stats, by db name and the filter they were generated with
+func statsFlags() []cli.Flag {
+	return append([]cli.Flag{
+		storeFlag,
+		&cli.StringFlag{
+			Name:     "database",
+			Aliases:  []string{"db"},
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    "region",
+			Aliases: []string{"rg"},
+			Usage:   "Region stats were restricted to, ie: eu - default to every region combined",
+		},
+		&cli.StringFlag{
+			Name:    "bracket",
+			Aliases: []string{"b"},
+			Usage:   "Pvp bracket stats were restricted to, ie: 3v3",
+		},
+		&cli.IntFlag{
+			Name:  "min-rating",
+			Usage: "Minimum pvp rating stats were restricted to, ie: 2400",
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "Pvp ladder rank stats were restricted to, ie: 500",
+		},
+		&cli.IntFlag{
+			Name:  "top-guilds",
+			Usage: "Raid guilds, ranked by kill time, stats were restricted to, ie: 100",
+		},
+		&cli.IntFlag{
+			Name:  "max-roster-rank",
+			Value: -1,
+			Usage: "Highest guild roster rank raid members were restricted to, ie: 3 for ranks 0 to 3",
+		},
+		&cli.BoolFlag{
+			Name:  "latest-crawl",
+			Usage: "Whether stats were restricted to characters listed by the latest complete crawl of their leatherboard",
+		},
+	}, queryFlags...)
+}
+
+// parseSnapshotFlag parse a snapshot flag given as a unix timestamp or a date with provided parser, 0 when unset
+func parseSnapshotFlag(c *cli.Context, name string, parse func(value string) (int64, error)) (int64, error) {
+	if c.String(name) == "" {
+		return 0, nil
+	}
+	return parse(c.String(name))
+}
+
+// openRetreiveStore open the store of provided db name for a retreive command, keeping dated profile copies when asked to
+func openRetreiveStore(c *cli.Context, dbname string) (databases.Store, error) {
+	store, err := databases.OpenStore(c.String("store"), databases.Scope{Source: dbname, Region: c.String("region")})
+	if err != nil {
+		return nil, err
+	}
+	if c.Bool("snapshot-profiles") {
+		return databases.WithProfileSnapshots(store, databases.NewSnapshot()), nil
+	}
+	return store, nil
+}
+
+// filterFromFlags return the stats filter described by the flags of a compute command
+func filterFromFlags(c *cli.Context) (databases.Filter, error) {
+	conditions, err := databases.ParseFilterExpression(c.String("filter"))
+	if err != nil {
+		return databases.Filter{}, err
+	}
+	return databases.Filter{
+		Region:      c.String("region"),
+		Bracket:     c.String("bracket"),
+		MinRating:   c.Int("min-rating"),
+		Top:         c.Int("top"),
+		TopGuilds:   c.Int("top-guilds"),
+		RosterRanks: c.Int("max-roster-rank") + 1,
+		LatestCrawl: c.Bool("latest-crawl"),
+		Query: databases.Query{
+			Class:   c.String("class"),
+			Spec:    c.String("spec"),
+			Realm:   c.String("realm"),
+			Faction: c.String("faction"),
+		},
+		Conditions: conditions,
+	}, nil
+}